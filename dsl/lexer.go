@@ -2,6 +2,8 @@ package dsl
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
 )
 
@@ -26,6 +28,15 @@ const (
 	TokenIntType
 	TokenBoolType
 	TokenFloatType
+	TokenDateTimeType
+	TokenDateType
+	TokenUUIDType
+	TokenBytesType
+	TokenInt64Type
+	TokenDecimalType
+	TokenEmailType
+	TokenURLType
+	TokenFileType
 	TokenColon
 	TokenParams
 	TokenQuery
@@ -34,6 +45,16 @@ const (
 	TokenNumberLiteral
 	TokenQuestionMark
 	TokenPath
+	TokenEquals
+	TokenPipe
+	TokenOpenAngle
+	TokenCloseAngle
+	TokenComma
+	TokenAt
+	TokenOpenParen
+	TokenCloseParen
+	TokenStringLiteral
+	TokenDocComment
 )
 
 var tokenNames = map[Token]string{
@@ -55,6 +76,15 @@ var tokenNames = map[Token]string{
 	TokenIntType:       "INT",
 	TokenBoolType:      "BOOL",
 	TokenFloatType:     "FLOAT",
+	TokenDateTimeType:  "DATETIME",
+	TokenDateType:      "DATE",
+	TokenUUIDType:      "UUID",
+	TokenBytesType:     "BYTES",
+	TokenInt64Type:     "INT64",
+	TokenDecimalType:   "DECIMAL",
+	TokenEmailType:     "EMAIL",
+	TokenURLType:       "URL",
+	TokenFileType:      "FILE",
 	TokenColon:         ":",
 	TokenParams:        "PARAMS",
 	TokenQuery:         "QUERY",
@@ -63,6 +93,16 @@ var tokenNames = map[Token]string{
 	TokenNumberLiteral: "NUMBER_LITERAL",
 	TokenQuestionMark:  "?",
 	TokenPath:          "PATH",
+	TokenEquals:        "=",
+	TokenPipe:          "|",
+	TokenOpenAngle:     "<",
+	TokenCloseAngle:    ">",
+	TokenComma:         ",",
+	TokenAt:            "@",
+	TokenOpenParen:     "(",
+	TokenCloseParen:    ")",
+	TokenStringLiteral: "STRING_LITERAL",
+	TokenDocComment:    "DOC_COMMENT",
 }
 
 func (t Token) String() string {
@@ -77,7 +117,13 @@ func (t Token) IsHTTPMethod() bool {
 }
 
 func (t Token) IsType() bool {
-	return t == TokenStringType || t == TokenIntType || t == TokenBoolType || t == TokenFloatType
+	switch t {
+	case TokenStringType, TokenIntType, TokenBoolType, TokenFloatType,
+		TokenDateTimeType, TokenDateType, TokenUUIDType, TokenBytesType,
+		TokenInt64Type, TokenDecimalType, TokenEmailType, TokenURLType, TokenFileType:
+		return true
+	}
+	return false
 }
 
 type Lexeme struct {
@@ -90,6 +136,8 @@ func (l Lexeme) String() string {
 	switch l.Type {
 	case TokenIdentifier, TokenPath, TokenNumberLiteral:
 		return fmt.Sprintf("%s(%s)", l.Type.String(), l.Value)
+	case TokenStringLiteral:
+		return fmt.Sprintf("%s(%q)", l.Type.String(), l.Value)
 	default:
 		return l.Type.String()
 	}
@@ -115,6 +163,10 @@ func isDigit(ch byte) bool {
 	return unicode.IsDigit(rune(ch))
 }
 
+func isIdentChar(ch byte) bool {
+	return isLetter(ch) || isDigit(ch) || ch == '_'
+}
+
 func isWhitespace(ch byte) bool {
 	return unicode.IsSpace(rune(ch))
 }
@@ -165,6 +217,24 @@ func lookupIdent(ident string) Token {
 		return TokenBoolType
 	case "float":
 		return TokenFloatType
+	case "datetime":
+		return TokenDateTimeType
+	case "date":
+		return TokenDateType
+	case "uuid":
+		return TokenUUIDType
+	case "bytes":
+		return TokenBytesType
+	case "int64":
+		return TokenInt64Type
+	case "decimal":
+		return TokenDecimalType
+	case "email":
+		return TokenEmailType
+	case "url":
+		return TokenURLType
+	case "file":
+		return TokenFileType
 	case "params":
 		return TokenParams
 	case "query":
@@ -192,6 +262,35 @@ func (l *Lexer) readNumber() string {
 	return l.input[startPos:l.pos]
 }
 
+// readString reads a double-quoted string literal whose opening quote has
+// already been consumed and returns its unescaped value.
+func (l *Lexer) readString() (string, bool) {
+	startPos := l.pos - 1
+	for {
+		switch l.readChar() {
+		case 0, '\n':
+			return "", false
+		case '\\':
+			l.consumeChar()
+		case '"':
+			value, err := strconv.Unquote(l.input[startPos:l.pos])
+			return value, err == nil
+		}
+	}
+}
+
+// readDocComment reads the text of a /// comment up to the end of the line.
+func (l *Lexer) readDocComment() string {
+	if l.peekChar() == ' ' {
+		l.consumeChar()
+	}
+	startPos := l.pos
+	for l.peekChar() != '\n' && l.peekChar() != 0 {
+		l.consumeChar()
+	}
+	return strings.TrimRight(l.input[startPos:l.pos], " \t\r")
+}
+
 func (l *Lexer) readPath() string {
 	startPos := l.pos - 1
 	for !isWhitespace(l.peekChar()) {
@@ -218,12 +317,46 @@ func (l *Lexer) NextToken() Lexeme {
 		return Lexeme{Type: TokenColon, Pos: l.pos - 1}
 	case '?':
 		return Lexeme{Type: TokenQuestionMark, Pos: l.pos - 1}
+	case '=':
+		return Lexeme{Type: TokenEquals, Pos: l.pos - 1}
+	case '|':
+		return Lexeme{Type: TokenPipe, Pos: l.pos - 1}
+	case '<':
+		return Lexeme{Type: TokenOpenAngle, Pos: l.pos - 1}
+	case '>':
+		return Lexeme{Type: TokenCloseAngle, Pos: l.pos - 1}
+	case ',':
+		return Lexeme{Type: TokenComma, Pos: l.pos - 1}
+	case '@':
+		return Lexeme{Type: TokenAt, Pos: l.pos - 1}
+	case '(':
+		return Lexeme{Type: TokenOpenParen, Pos: l.pos - 1}
+	case ')':
+		return Lexeme{Type: TokenCloseParen, Pos: l.pos - 1}
+	case '"':
+		startPos := l.pos - 1
+		if value, ok := l.readString(); ok {
+			return Lexeme{Type: TokenStringLiteral, Pos: startPos, Value: value}
+		}
+	case '-':
+		if isDigit(l.peekChar()) {
+			startPos := l.pos - 1
+			l.consumeChar()
+			number := l.readNumber()
+			return Lexeme{Type: TokenNumberLiteral, Pos: startPos, Value: "-" + number}
+		}
 	case '[':
 		return Lexeme{Type: TokenOpenBracket, Pos: l.pos - 1}
 	case ']':
 		return Lexeme{Type: TokenCloseBracket, Pos: l.pos - 1}
 	case '/':
 		if l.peekChar() == '/' {
+			startPos := l.pos - 1
+			l.consumeChar()
+			if l.peekChar() == '/' {
+				l.consumeChar()
+				return Lexeme{Type: TokenDocComment, Pos: startPos, Value: l.readDocComment()}
+			}
 			for c != '\n' && c != 0 {
 				c = l.readChar()
 			}
@@ -235,8 +368,15 @@ func (l *Lexer) NextToken() Lexeme {
 	default:
 		if isLetter(c) {
 			startPos := l.pos - 1
-			for isLetter(l.peekChar()) || isDigit(l.peekChar()) {
+			for isIdentChar(l.peekChar()) {
+				l.consumeChar()
+			}
+			// A qualified name such as billing.Invoice is a single identifier.
+			for l.peekChar() == '.' && l.pos+1 < len(l.input) && isLetter(l.input[l.pos+1]) {
 				l.consumeChar()
+				for isIdentChar(l.peekChar()) {
+					l.consumeChar()
+				}
 			}
 			ident := l.input[startPos:l.pos]
 			tokenType := lookupIdent(ident)
@@ -0,0 +1,37 @@
+package dsl_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+)
+
+func TestDocCommentTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{"single line", "/// A user.\ntype", []string{"A user."}},
+		{"multiple lines", "/// A user.\n///\n/// Signs up.\ntype", []string{"A user.", "", "Signs up."}},
+		{"no space", "///A user.\ntype", []string{"A user."}},
+		{"indented text", "///   indented\ntype", []string{"  indented"}},
+		{"trailing whitespace", "/// A user. \t\ntype", []string{"A user."}},
+		{"at end of input", "/// A user.", []string{"A user."}},
+		{"plain comment", "// not a doc\ntype", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs []string
+			for _, tok := range dsl.NewLexer(tt.input).Tokenize() {
+				if tok.Type == dsl.TokenDocComment {
+					docs = append(docs, tok.Value)
+				}
+			}
+			if !slices.Equal(docs, tt.want) {
+				t.Errorf("expected doc comments %q, got %q", tt.want, docs)
+			}
+		})
+	}
+}
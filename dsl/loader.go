@@ -0,0 +1,99 @@
+package dsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/printchard/scapi/spec"
+)
+
+// sourceFile is a parsed spec file along with the path it was read from.
+type sourceFile struct {
+	path string
+	spec *Spec
+}
+
+// loader reads a spec file and, transitively, the files it imports.
+type loader struct {
+	// files holds the loaded files, each after the files it imports.
+	files  []sourceFile
+	loaded map[string]bool
+	// stack holds the files being loaded, for cycle detection.
+	stack []sourceFile
+}
+
+func inFile(path string, err error) error {
+	if path == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %v", path, err)
+}
+
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// load reads the file at path and the files it imports. Imports are
+// resolved relative to the directory of the importing file.
+func (l *loader) load(path string) error {
+	for i, file := range l.stack {
+		if sameFile(file.path, path) {
+			cycle := []string{}
+			for _, f := range l.stack[i:] {
+				cycle = append(cycle, f.path)
+			}
+			cycle = append(cycle, path)
+			return fmt.Errorf("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if l.loaded[key] {
+		return nil
+	}
+
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	s, err := NewParserFromString(string(input)).Parse()
+	if err != nil {
+		return inFile(path, err)
+	}
+	if len(l.stack) > 0 && s.Name != "" {
+		return inFile(path, fmt.Errorf("imported file cannot declare api %s", s.Name))
+	}
+
+	l.stack = append(l.stack, sourceFile{path: path, spec: s})
+	for _, imp := range s.Imports {
+		importPath := filepath.Join(filepath.Dir(path), imp)
+		if err := l.load(importPath); err != nil {
+			if os.IsNotExist(err) {
+				return inFile(path, fmt.Errorf("cannot import %q: file not found", imp))
+			}
+			return err
+		}
+	}
+	l.stack = l.stack[:len(l.stack)-1]
+
+	l.loaded[key] = true
+	l.files = append(l.files, sourceFile{path: path, spec: s})
+	return nil
+}
+
+// NewTranslatorFromFile translates the spec file at path, together with the
+// files it imports, into a single API. Diagnostics name the offending file.
+func NewTranslatorFromFile(path string) (*spec.APISpec, error) {
+	l := &loader{loaded: make(map[string]bool)}
+	if err := l.load(path); err != nil {
+		return nil, err
+	}
+	t := &Translator{}
+	return t.translateFiles(l.files)
+}
@@ -0,0 +1,113 @@
+package dsl_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+)
+
+func TestNewTranslatorFromFile(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		// wantErr is a substring of the expected error, or empty if the
+		// files should translate.
+		wantErr string
+	}{
+		{
+			name: "relative import",
+			files: map[string]string{
+				"api/main.scapi": `api Shop
+import "../common/money.scapi"
+
+type Item {
+  price: billing.Money
+}`,
+				"common/money.scapi": `package billing
+
+type Money {
+  amount: integer
+}`,
+			},
+		},
+		{
+			name: "import cycle",
+			files: map[string]string{
+				"api/main.scapi": `api Shop
+import "a.scapi"`,
+				"api/a.scapi": `package a
+import "b.scapi"`,
+				"api/b.scapi": `package b
+import "a.scapi"`,
+			},
+			wantErr: "import cycle",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"api/main.scapi": `api Shop
+import "missing.scapi"`,
+			},
+			wantErr: `cannot import "missing.scapi": file not found`,
+		},
+		{
+			name: "api in imported file",
+			files: map[string]string{
+				"api/main.scapi": `api Shop
+import "other.scapi"`,
+				"api/other.scapi": `api Other`,
+			},
+			wantErr: "imported file cannot declare api Other",
+		},
+		{
+			name: "duplicate qualified name",
+			files: map[string]string{
+				"api/main.scapi": `api Shop
+import "a.scapi"
+import "b.scapi"`,
+				"api/a.scapi": `package billing
+
+type Money {
+  amount: integer
+}`,
+				"api/b.scapi": `package billing
+
+type Money {
+  cents: integer
+}`,
+			},
+			wantErr: "duplicate type declaration: billing.Money",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			api, err := dsl.NewTranslatorFromFile(filepath.Join(dir, "api/main.scapi"))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected valid spec, got error: %v", err)
+				}
+				if _, ok := api.Types["billing.Money"]; !ok {
+					t.Errorf("expected imported type billing.Money")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -3,10 +3,16 @@ package dsl
 import (
 	"fmt"
 	"strconv"
+	"strings"
 )
 
+// Spec is a parsed spec file. Name is empty for files that only declare
+// types for others to import.
 type Spec struct {
+	Doc          string
 	Name         string
+	Package      string
+	Imports      []string
 	Declarations []Declaration
 }
 
@@ -15,17 +21,77 @@ type Declaration interface {
 }
 
 type TypeDeclaration struct {
+	Doc               string
+	Annotations       []AnnotationDeclaration
 	Identifier        string
+	TypeParams        []string
+	Parents           []TypeExpression
 	FieldDeclarations []FieldDeclaration
 }
 
 func (t TypeDeclaration) isDeclaration() {}
 
+type AliasDeclaration struct {
+	Doc         string
+	Annotations []AnnotationDeclaration
+	Identifier  string
+	Type        SimpleTypeExpression
+}
+
+func (a AliasDeclaration) isDeclaration() {}
+
+// DerivedTypeDeclaration declares an object type computed from Base by one
+// of the Pick, Omit or Partial operators.
+type DerivedTypeDeclaration struct {
+	Doc         string
+	Annotations []AnnotationDeclaration
+	Identifier  string
+	Operator    string
+	Base        TypeExpression
+	Fields      []string
+}
+
+func (d DerivedTypeDeclaration) isDeclaration() {}
+
+type EnumDeclaration struct {
+	Doc         string
+	Annotations []AnnotationDeclaration
+	Identifier  string
+	Members     []string
+	// MemberAnnotations holds the annotations following each member.
+	MemberAnnotations map[string][]AnnotationDeclaration
+}
+
+func (e EnumDeclaration) isDeclaration() {}
+
+type UnionDeclaration struct {
+	Doc           string
+	Annotations   []AnnotationDeclaration
+	Identifier    string
+	Variants      []string
+	Discriminator string
+}
+
+func (u UnionDeclaration) isDeclaration() {}
+
 type FieldDeclaration struct {
 	Identifier string
 	Type       TypeExpression
 	Optional   bool
 	Nullable   bool
+	Doc        string
+	// Default is the literal following "=", if any.
+	Default     *Lexeme
+	Annotations []AnnotationDeclaration
+}
+
+// AnnotationDeclaration is metadata such as @minLength(1) or @internal.
+// Arguments are number, string or identifier literals, and NamedArgs holds
+// those given as name=value.
+type AnnotationDeclaration struct {
+	Name      string
+	Args      []Lexeme
+	NamedArgs map[string]Lexeme
 }
 
 type TypeExpression interface {
@@ -38,22 +104,57 @@ type SimpleTypeExpression struct {
 
 func (s SimpleTypeExpression) isTypeExpression() {}
 
+type GenericTypeExpression struct {
+	Name string
+	Args []TypeExpression
+}
+
+func (g GenericTypeExpression) isTypeExpression() {}
+
 type ArrayTypeExpression struct {
 	ElementType TypeExpression
 }
 
 func (a ArrayTypeExpression) isTypeExpression() {}
 
+type ObjectTypeExpression struct {
+	Fields []FieldDeclaration
+}
+
+func (o ObjectTypeExpression) isTypeExpression() {}
+
+type MapTypeExpression struct {
+	KeyType   SimpleTypeExpression
+	ValueType TypeExpression
+}
+
+func (m MapTypeExpression) isTypeExpression() {}
+
 type EndpointDeclaration struct {
-	Name   string
-	Method string
-	Path   string
-	Verb   string
-	Body   []EndpointFieldDeclaration
+	Doc         string
+	Annotations []AnnotationDeclaration
+	Name        string
+	Method      string
+	Path        string
+	Verb        string
+	Body        []EndpointFieldDeclaration
 }
 
 func (e EndpointDeclaration) isDeclaration() {}
 
+// ChannelDeclaration declares a WebSocket channel. Client and Server are the
+// types of the messages each side sends.
+type ChannelDeclaration struct {
+	Doc         string
+	Annotations []AnnotationDeclaration
+	Name        string
+	Path        string
+	Client      TypeExpression
+	Server      TypeExpression
+}
+
+func (c ChannelDeclaration) isDeclaration() {}
+
 type EndpointFieldDeclaration interface {
 	isEndpointField()
 }
@@ -70,16 +171,39 @@ type QueryDeclaration struct {
 
 func (q QueryDeclaration) isEndpointField() {}
 
+// HeadersDeclaration declares request headers, keyed by header name.
+type HeadersDeclaration struct {
+	Fields []FieldDeclaration
+}
+
+func (h HeadersDeclaration) isEndpointField() {}
+
+// CookiesDeclaration declares request cookies, keyed by cookie name.
+type CookiesDeclaration struct {
+	Fields []FieldDeclaration
+}
+
+func (c CookiesDeclaration) isEndpointField() {}
+
+// BodyDeclaration declares the request body. ContentType is empty for JSON
+// bodies.
 type BodyDeclaration struct {
-	Type     SimpleTypeExpression
-	Optional bool
+	Type        TypeExpression
+	Optional    bool
+	ContentType string
 }
 
 func (b BodyDeclaration) isEndpointField() {}
 
+// ResponseDeclaration declares a response. Binary responses have a
+// ContentType instead of a Type. Streamed responses have the format of
+// their stream of Type events.
 type ResponseDeclaration struct {
-	Code int
-	Type TypeExpression
+	Code        int
+	Type        TypeExpression
+	ContentType string
+	Stream      string
+	Headers     []FieldDeclaration
 }
 
 func (r ResponseDeclaration) isEndpointField() {}
@@ -90,7 +214,29 @@ type Parser struct {
 	pos    int
 }
 
+// nextTokenPos returns the position of the next token that is not a doc
+// comment. Doc comments are only read where a node may be documented.
+func (p *Parser) nextTokenPos() int {
+	pos := p.pos
+	for pos < len(p.tokens) && p.tokens[pos].Type == TokenDocComment {
+		pos++
+	}
+	return pos
+}
+
+// parseDocComment consumes the doc comment lines preceding the next token
+// and returns their text.
+func (p *Parser) parseDocComment() string {
+	var lines []string
+	for p.pos < len(p.tokens) && p.tokens[p.pos].Type == TokenDocComment {
+		lines = append(lines, p.tokens[p.pos].Value)
+		p.pos++
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (p *Parser) readToken() Lexeme {
+	p.pos = p.nextTokenPos()
 	if p.pos >= len(p.tokens) {
 		return Lexeme{Type: TokenEOF, Pos: p.pos}
 	}
@@ -100,14 +246,15 @@ func (p *Parser) readToken() Lexeme {
 }
 
 func (p *Parser) peekToken() Lexeme {
-	if p.pos >= len(p.tokens) {
-		return Lexeme{Type: TokenEOF, Pos: p.pos}
+	pos := p.nextTokenPos()
+	if pos >= len(p.tokens) {
+		return Lexeme{Type: TokenEOF, Pos: pos}
 	}
-	return p.tokens[p.pos]
+	return p.tokens[pos]
 }
 
 func (p *Parser) consumeToken() {
-	p.pos++
+	p.pos = p.nextTokenPos() + 1
 }
 
 func (p *Parser) match(expected Token) error {
@@ -121,11 +268,241 @@ func (p *Parser) match(expected Token) error {
 	return nil
 }
 
+func simpleTypeFromToken(tok Lexeme) (SimpleTypeExpression, bool) {
+	if tok.Type != TokenIdentifier && !tok.Type.IsType() {
+		return SimpleTypeExpression{}, false
+	}
+	return SimpleTypeExpression{Name: tok.Value}, true
+}
+
+func (p *Parser) parseTypeExpression() (TypeExpression, error) {
+	if p.peekToken().Type == TokenOpenBrace {
+		return p.parseObjectType()
+	}
+	if mapToken := p.peekToken(); p.isMapType() {
+		return nil, fmt.Errorf("unexpected map type at position %d, map types are only allowed as field types", mapToken.Pos)
+	}
+	typeToken := p.readToken()
+	if typeToken.Type == TokenOpenBracket {
+		elemType, err := p.parseTypeExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.match(TokenCloseBracket); err != nil {
+			return nil, err
+		}
+		return ArrayTypeExpression{ElementType: elemType}, nil
+	}
+	simpleType, ok := simpleTypeFromToken(typeToken)
+	if !ok {
+		return nil, fmt.Errorf("unexpected token %s at position %d, expected type", typeToken.String(), typeToken.Pos)
+	}
+	if typeToken.Type == TokenIdentifier && p.peekToken().Type == TokenOpenAngle {
+		args, err := p.parseTypeArguments()
+		if err != nil {
+			return nil, err
+		}
+		return GenericTypeExpression{Name: simpleType.Name, Args: args}, nil
+	}
+	return simpleType, nil
+}
+
+func (p *Parser) parseTypeArguments() ([]TypeExpression, error) {
+	if err := p.match(TokenOpenAngle); err != nil {
+		return nil, err
+	}
+	args := []TypeExpression{}
+	for {
+		arg, err := p.parseTypeExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.peekToken().Type != TokenComma {
+			break
+		}
+		p.consumeToken()
+	}
+	if err := p.match(TokenCloseAngle); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func (p *Parser) parseTypeParameters() ([]string, error) {
+	if err := p.match(TokenOpenAngle); err != nil {
+		return nil, err
+	}
+	params := []string{}
+	for {
+		paramToken := p.readToken()
+		if paramToken.Type != TokenIdentifier {
+			return nil, fmt.Errorf("unexpected token %s at position %d, expected type parameter", paramToken.String(), paramToken.Pos)
+		}
+		params = append(params, paramToken.Value)
+		if p.peekToken().Type != TokenComma {
+			break
+		}
+		p.consumeToken()
+	}
+	if err := p.match(TokenCloseAngle); err != nil {
+		return nil, err
+	}
+	return params, nil
+}
+
+// parseObjectType parses an inline object type enclosed in braces.
+func (p *Parser) parseObjectType() (TypeExpression, error) {
+	if err := p.match(TokenOpenBrace); err != nil {
+		return nil, err
+	}
+
+	fieldDecls, err := p.parseFieldDeclarations()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.match(TokenCloseBrace); err != nil {
+		return nil, err
+	}
+	return ObjectTypeExpression{Fields: fieldDecls}, nil
+}
+
+// isMapType reports whether a map type such as map<string, Price> follows.
+// A type called map is only taken for the keyword if no type arguments
+// follow it.
+func (p *Parser) isMapType() bool {
+	tok := p.peekToken()
+	if tok.Type != TokenIdentifier || tok.Value != "map" {
+		return false
+	}
+	start := p.pos
+	p.consumeToken()
+	isMap := p.peekToken().Type == TokenOpenAngle
+	p.pos = start
+	return isMap
+}
+
+// parseMapType parses a map type such as map<string, Price>.
+func (p *Parser) parseMapType() (TypeExpression, error) {
+	// The caller has already seen the map keyword.
+	p.consumeToken()
+	if err := p.match(TokenOpenAngle); err != nil {
+		return nil, err
+	}
+	keyToken := p.readToken()
+	keyType, ok := simpleTypeFromToken(keyToken)
+	if !ok {
+		return nil, fmt.Errorf("unexpected token %s at position %d, expected map key type", keyToken.String(), keyToken.Pos)
+	}
+	if err := p.match(TokenComma); err != nil {
+		return nil, err
+	}
+	valueType, err := p.parseTypeExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.match(TokenCloseAngle); err != nil {
+		return nil, err
+	}
+	return MapTypeExpression{KeyType: keyType, ValueType: valueType}, nil
+}
+
+func (p *Parser) parseFieldType() (TypeExpression, error) {
+	if p.isMapType() {
+		return p.parseMapType()
+	}
+	return p.parseTypeExpression()
+}
+
 func (p *Parser) parseFieldDeclarations() ([]FieldDeclaration, error) {
+	return p.parseFields(false)
+}
+
+func isHeadersKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "headers"
+}
+
+func isChannelKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "channel"
+}
+
+func isCookiesKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "cookies"
+}
+
+// The keywords starting declarations are contextual, so that they may still
+// name fields.
+
+func isEnumKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "enum"
+}
+
+func isUnionKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "union"
+}
+
+func isPackageKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "package"
+}
+
+func isImportKeyword(tok Lexeme) bool {
+	return tok.Type == TokenIdentifier && tok.Value == "import"
+}
+
+// bodyEncodings maps the keywords naming a body encoding to its content type.
+var bodyEncodings = map[string]string{
+	"form":      "application/x-www-form-urlencoded",
+	"multipart": "multipart/form-data",
+}
+
+// parseBodyContentType parses the content type of a body declaration, which
+// is either a quoted media type or an encoding keyword. An identifier is
+// only a keyword if a type follows it, so types called form or multipart
+// can still be bodies. It returns an empty string for JSON bodies.
+func (p *Parser) parseBodyContentType() string {
+	tok := p.peekToken()
+	if tok.Type == TokenStringLiteral {
+		p.consumeToken()
+		return tok.Value
+	}
+	contentType, ok := bodyEncodings[tok.Value]
+	if tok.Type != TokenIdentifier || !ok {
+		return ""
+	}
+	start := p.pos
+	p.consumeToken()
+	next := p.peekToken()
+	if next.Type == TokenOpenBrace || next.Type == TokenOpenBracket || next.Type == TokenIdentifier || next.Type.IsType() {
+		return contentType
+	}
+	p.pos = start
+	return ""
+}
+
+// parseHeadersBlock parses a headers or cookies block. Names may be quoted
+// since header names such as X-Request-Id are not identifiers.
+func (p *Parser) parseHeadersBlock() ([]FieldDeclaration, error) {
+	p.consumeToken()
+	if err := p.match(TokenOpenBrace); err != nil {
+		return nil, err
+	}
+	fieldDecls, err := p.parseFields(true)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.match(TokenCloseBrace); err != nil {
+		return nil, err
+	}
+	return fieldDecls, nil
+}
+
+func (p *Parser) parseFields(quotedNames bool) ([]FieldDeclaration, error) {
 	fieldDecls := []FieldDeclaration{}
 	for {
+		doc := p.parseDocComment()
+		// Primitive type keywords such as email or url are valid field names.
 		next := p.peekToken()
-		if next.Type != TokenIdentifier {
+		if next.Type != TokenIdentifier && !next.Type.IsType() && !(quotedNames && next.Type == TokenStringLiteral) {
 			break
 		}
 
@@ -141,33 +518,9 @@ func (p *Parser) parseFieldDeclarations() ([]FieldDeclaration, error) {
 			return nil, err
 		}
 
-		typeToken := p.readToken()
-		var typeExpr TypeExpression
-
-		switch typeToken.Type {
-		case TokenIdentifier:
-			typeExpr = SimpleTypeExpression{Name: typeToken.Value}
-		case TokenOpenBracket:
-			elemTypeToken := p.readToken()
-			if elemTypeToken.Type != TokenIdentifier {
-				return nil, fmt.Errorf("unexpected token %s at position %d, expected type identifier", elemTypeToken.String(), elemTypeToken.Pos)
-			}
-			if err := p.match(TokenCloseBracket); err != nil {
-				return nil, err
-			}
-			typeExpr = ArrayTypeExpression{
-				ElementType: SimpleTypeExpression{Name: elemTypeToken.Value},
-			}
-		case TokenStringType:
-			typeExpr = SimpleTypeExpression{Name: "string"}
-		case TokenIntType:
-			typeExpr = SimpleTypeExpression{Name: "integer"}
-		case TokenBoolType:
-			typeExpr = SimpleTypeExpression{Name: "boolean"}
-		case TokenFloatType:
-			typeExpr = SimpleTypeExpression{Name: "float"}
-		default:
-			return nil, fmt.Errorf("unexpected token %s at position %d, expected type", typeToken.String(), typeToken.Pos)
+		typeExpr, err := p.parseFieldType()
+		if err != nil {
+			return nil, err
 		}
 
 		nullable := false
@@ -176,57 +529,384 @@ func (p *Parser) parseFieldDeclarations() ([]FieldDeclaration, error) {
 			nullable = true
 		}
 
+		var defaultValue *Lexeme
+		if p.peekToken().Type == TokenEquals {
+			p.consumeToken()
+			valueToken := p.readToken()
+			switch valueToken.Type {
+			case TokenNumberLiteral, TokenStringLiteral, TokenIdentifier:
+				defaultValue = &valueToken
+			default:
+				return nil, fmt.Errorf("unexpected token %s at position %d, expected default value", valueToken.String(), valueToken.Pos)
+			}
+		}
+
+		annotations, err := p.parseAnnotations()
+		if err != nil {
+			return nil, err
+		}
+
 		fieldDecls = append(fieldDecls, FieldDeclaration{
-			Identifier: fieldNameToken.Value,
-			Type:       typeExpr,
-			Optional:   optional,
-			Nullable:   nullable,
+			Identifier:  fieldNameToken.Value,
+			Type:        typeExpr,
+			Optional:    optional,
+			Nullable:    nullable,
+			Doc:         doc,
+			Default:     defaultValue,
+			Annotations: annotations,
 		})
 	}
 	return fieldDecls, nil
 }
 
-func (p *Parser) parseTypeDeclarations() ([]TypeDeclaration, error) {
-	token := p.peekToken()
-	if token.Type != TokenType {
-		return nil, fmt.Errorf("unexpected token %s at position %d, expected 'type'", token.String(), token.Pos)
+func isLiteral(tok Lexeme) bool {
+	return tok.Type == TokenNumberLiteral || tok.Type == TokenStringLiteral || tok.Type == TokenIdentifier
+}
+
+func (p *Parser) parseAnnotations() ([]AnnotationDeclaration, error) {
+	var annotations []AnnotationDeclaration
+	for p.peekToken().Type == TokenAt {
+		p.consumeToken()
+		nameToken := p.readToken()
+		if nameToken.Type != TokenIdentifier {
+			return nil, fmt.Errorf("unexpected token %s at position %d, expected annotation name", nameToken.String(), nameToken.Pos)
+		}
+		annotation := AnnotationDeclaration{Name: nameToken.Value}
+		if p.peekToken().Type == TokenOpenParen {
+			p.consumeToken()
+			for i := 0; p.peekToken().Type != TokenCloseParen; i++ {
+				if i > 0 {
+					if err := p.match(TokenComma); err != nil {
+						return nil, err
+					}
+				}
+				argToken := p.readToken()
+				if !isLiteral(argToken) {
+					return nil, fmt.Errorf("unexpected token %s at position %d, expected literal", argToken.String(), argToken.Pos)
+				}
+				if argToken.Type != TokenIdentifier || p.peekToken().Type != TokenEquals {
+					annotation.Args = append(annotation.Args, argToken)
+					continue
+				}
+				p.consumeToken()
+				valueToken := p.readToken()
+				if !isLiteral(valueToken) {
+					return nil, fmt.Errorf("unexpected token %s at position %d, expected literal", valueToken.String(), valueToken.Pos)
+				}
+				if _, exists := annotation.NamedArgs[argToken.Value]; exists {
+					return nil, fmt.Errorf("duplicate argument %s at position %d", argToken.Value, argToken.Pos)
+				}
+				if annotation.NamedArgs == nil {
+					annotation.NamedArgs = make(map[string]Lexeme)
+				}
+				annotation.NamedArgs[argToken.Value] = valueToken
+			}
+			p.consumeToken()
+		}
+		annotations = append(annotations, annotation)
 	}
+	return annotations, nil
+}
 
-	typeDecls := []TypeDeclaration{}
-	for {
-		if err := p.match(TokenType); err != nil {
+func (p *Parser) parseTypeDeclaration() (Declaration, error) {
+	if err := p.match(TokenType); err != nil {
+		return nil, err
+	}
+
+	typeNameToken := p.readToken()
+	if typeNameToken.Type != TokenIdentifier {
+		return nil, fmt.Errorf("unexpected token %s at position %d, expected type name", typeNameToken.String(), typeNameToken.Pos)
+	}
+
+	if p.peekToken().Type == TokenEquals {
+		p.consumeToken()
+		aliasToken := p.peekToken()
+		aliased, err := p.parseTypeExpression()
+		if err != nil {
 			return nil, err
 		}
-
-		typeNameToken := p.readToken()
-		if typeNameToken.Type != TokenIdentifier {
-			return nil, fmt.Errorf("unexpected token %s at position %d, expected type name", typeNameToken.String(), typeNameToken.Pos)
+		switch t := aliased.(type) {
+		case SimpleTypeExpression:
+			return AliasDeclaration{
+				Identifier: typeNameToken.Value,
+				Type:       t,
+			}, nil
+		case GenericTypeExpression:
+			if isTypeOperator(t.Name) {
+				return newDerivedTypeDeclaration(typeNameToken.Value, t, aliasToken.Pos)
+			}
 		}
+		return nil, fmt.Errorf("unexpected type at position %d, expected aliased type or type operator", aliasToken.Pos)
+	}
 
-		if err := p.match(TokenOpenBrace); err != nil {
+	var typeParams []string
+	if p.peekToken().Type == TokenOpenAngle {
+		var err error
+		typeParams, err = p.parseTypeParameters()
+		if err != nil {
 			return nil, err
 		}
+	}
 
-		fieldDecls, err := p.parseFieldDeclarations()
+	var parents []TypeExpression
+	if next := p.peekToken(); next.Type == TokenIdentifier && next.Value == "extends" {
+		p.consumeToken()
+		for {
+			parent, err := p.parseTypeExpression()
+			if err != nil {
+				return nil, err
+			}
+			parents = append(parents, parent)
+			if p.peekToken().Type != TokenComma {
+				break
+			}
+			p.consumeToken()
+		}
+	}
+
+	if err := p.match(TokenOpenBrace); err != nil {
+		return nil, err
+	}
+
+	fieldDecls, err := p.parseFieldDeclarations()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.match(TokenCloseBrace); err != nil {
+		return nil, err
+	}
+
+	return TypeDeclaration{
+		Identifier:        typeNameToken.Value,
+		TypeParams:        typeParams,
+		Parents:           parents,
+		FieldDeclarations: fieldDecls,
+	}, nil
+}
+
+func isTypeOperator(name string) bool {
+	return name == "Pick" || name == "Omit" || name == "Partial"
+}
+
+func newDerivedTypeDeclaration(name string, op GenericTypeExpression, pos int) (DerivedTypeDeclaration, error) {
+	switch {
+	case op.Name == "Partial" && len(op.Args) != 1:
+		return DerivedTypeDeclaration{}, fmt.Errorf("Partial at position %d expects a single type argument", pos)
+	case op.Name != "Partial" && len(op.Args) < 2:
+		return DerivedTypeDeclaration{}, fmt.Errorf("%s at position %d expects a type and at least one field name", op.Name, pos)
+	}
+	if base, ok := op.Args[0].(GenericTypeExpression); ok && isTypeOperator(base.Name) {
+		return DerivedTypeDeclaration{}, fmt.Errorf("%s at position %d cannot be applied to another type operator", op.Name, pos)
+	}
+	fields := []string{}
+	for _, arg := range op.Args[1:] {
+		field, ok := arg.(SimpleTypeExpression)
+		if !ok {
+			return DerivedTypeDeclaration{}, fmt.Errorf("%s at position %d expects field names after the type", op.Name, pos)
+		}
+		fields = append(fields, field.Name)
+	}
+	return DerivedTypeDeclaration{
+		Identifier: name,
+		Operator:   op.Name,
+		Base:       op.Args[0],
+		Fields:     fields,
+	}, nil
+}
+
+func (p *Parser) parseEnumDeclaration() (EnumDeclaration, error) {
+	// The caller has already seen the enum keyword.
+	p.consumeToken()
+
+	enumNameToken := p.readToken()
+	if enumNameToken.Type != TokenIdentifier {
+		return EnumDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected enum name", enumNameToken.String(), enumNameToken.Pos)
+	}
+
+	if err := p.match(TokenOpenBrace); err != nil {
+		return EnumDeclaration{}, err
+	}
+
+	members := []string{}
+	memberAnnotations := make(map[string][]AnnotationDeclaration)
+	for p.peekToken().Type == TokenIdentifier {
+		member := p.readToken().Value
+		members = append(members, member)
+		annotations, err := p.parseAnnotations()
 		if err != nil {
-			return nil, err
+			return EnumDeclaration{}, err
+		}
+		if len(annotations) > 0 {
+			memberAnnotations[member] = annotations
 		}
+	}
 
-		if err := p.match(TokenCloseBrace); err != nil {
+	if err := p.match(TokenCloseBrace); err != nil {
+		return EnumDeclaration{}, err
+	}
+
+	return EnumDeclaration{
+		Identifier:        enumNameToken.Value,
+		Members:           members,
+		MemberAnnotations: memberAnnotations,
+	}, nil
+}
+
+func (p *Parser) parseUnionDeclaration() (UnionDeclaration, error) {
+	// The caller has already seen the union keyword.
+	p.consumeToken()
+
+	unionNameToken := p.readToken()
+	if unionNameToken.Type != TokenIdentifier {
+		return UnionDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected union name", unionNameToken.String(), unionNameToken.Pos)
+	}
+
+	if err := p.match(TokenEquals); err != nil {
+		return UnionDeclaration{}, err
+	}
+
+	variants := []string{}
+	for {
+		variantToken := p.readToken()
+		if variantToken.Type != TokenIdentifier {
+			return UnionDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected variant type name", variantToken.String(), variantToken.Pos)
+		}
+		variants = append(variants, variantToken.Value)
+		if p.peekToken().Type != TokenPipe {
+			break
+		}
+		p.consumeToken()
+	}
+
+	byToken := p.readToken()
+	if byToken.Type != TokenIdentifier || byToken.Value != "by" {
+		return UnionDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected 'by'", byToken.String(), byToken.Pos)
+	}
+
+	discriminatorToken := p.readToken()
+	if discriminatorToken.Type != TokenIdentifier {
+		return UnionDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected discriminator field name", discriminatorToken.String(), discriminatorToken.Pos)
+	}
+
+	return UnionDeclaration{
+		Identifier:    unionNameToken.Value,
+		Variants:      variants,
+		Discriminator: discriminatorToken.Value,
+	}, nil
+}
+
+func (p *Parser) parseTypeDeclarations() ([]Declaration, error) {
+	decls := []Declaration{}
+	for {
+		start := p.pos
+		doc := p.parseDocComment()
+		annotations, err := p.parseAnnotations()
+		if err != nil {
 			return nil, err
 		}
+		switch tok := p.peekToken(); {
+		case tok.Type == TokenType:
+			decl, err := p.parseTypeDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			switch d := decl.(type) {
+			case TypeDeclaration:
+				d.Doc, d.Annotations = doc, annotations
+				decl = d
+			case AliasDeclaration:
+				d.Doc, d.Annotations = doc, annotations
+				decl = d
+			case DerivedTypeDeclaration:
+				d.Doc, d.Annotations = doc, annotations
+				decl = d
+			}
+			decls = append(decls, decl)
+		case isEnumKeyword(tok):
+			decl, err := p.parseEnumDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			decl.Doc, decl.Annotations = doc, annotations
+			decls = append(decls, decl)
+		case isUnionKeyword(tok):
+			decl, err := p.parseUnionDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			decl.Doc, decl.Annotations = doc, annotations
+			decls = append(decls, decl)
+		default:
+			// Leave the doc comment for the declaration that follows.
+			p.pos = start
+			return decls, nil
+		}
+	}
+}
 
-		typeDecls = append(typeDecls, TypeDeclaration{
-			Identifier:        typeNameToken.Value,
-			FieldDeclarations: fieldDecls,
-		})
+// parseBinaryResponse parses binary("media/type") and returns the media
+// type, or an empty string if the response is not binary. A type called
+// binary is only taken for the keyword if a parenthesis follows it.
+func (p *Parser) parseBinaryResponse() (string, error) {
+	tok := p.peekToken()
+	if tok.Type != TokenIdentifier || tok.Value != "binary" {
+		return "", nil
+	}
+	start := p.pos
+	p.consumeToken()
+	if p.peekToken().Type != TokenOpenParen {
+		p.pos = start
+		return "", nil
+	}
+	p.consumeToken()
+	contentType := p.readToken()
+	if contentType.Type != TokenStringLiteral {
+		return "", fmt.Errorf("unexpected token %s at position %d, expected content type", contentType.String(), contentType.Pos)
+	}
+	if err := p.match(TokenCloseParen); err != nil {
+		return "", err
+	}
+	return contentType.Value, nil
+}
 
-		next := p.peekToken()
-		if next.Type != TokenType {
-			break
+// parseStreamFormat parses the stream keyword of a streamed response, with
+// an optional format such as stream(ndjson), and returns the format. Streams
+// without a format are server-sent events. It returns an empty string if the
+// response is not streamed; a type called stream is only taken for the
+// keyword if a format or a type follows it.
+func (p *Parser) parseStreamFormat() (string, error) {
+	tok := p.peekToken()
+	if tok.Type != TokenIdentifier || tok.Value != "stream" {
+		return "", nil
+	}
+	start := p.pos
+	p.consumeToken()
+	next := p.peekToken()
+	if next.Type != TokenOpenParen {
+		if next.Type == TokenOpenBrace || next.Type == TokenOpenBracket || next.Type.IsType() || (next.Type == TokenIdentifier && !isHeadersKeyword(next)) {
+			return "sse", nil
 		}
+		p.pos = start
+		return "", nil
+	}
+	p.consumeToken()
+	format := p.readToken()
+	if format.Type != TokenIdentifier {
+		return "", fmt.Errorf("unexpected token %s at position %d, expected stream format", format.String(), format.Pos)
+	}
+	if err := p.match(TokenCloseParen); err != nil {
+		return "", err
 	}
-	return typeDecls, nil
+	return format.Value, nil
+}
+
+// isResponseEnd reports whether the response being parsed has no body type,
+// because its headers, the next response or the end of the responses follow
+// its status code.
+func (p *Parser) isResponseEnd() bool {
+	tok := p.peekToken()
+	return tok.Type == TokenNumberLiteral || tok.Type == TokenCloseBrace || isHeadersKeyword(tok)
 }
 
 func (p *Parser) parseResponseDeclarations() ([]ResponseDeclaration, error) {
@@ -244,9 +924,28 @@ func (p *Parser) parseResponseDeclarations() ([]ResponseDeclaration, error) {
 		}
 		p.consumeToken()
 
-		typeToken := p.readToken()
-		if typeToken.Type != TokenIdentifier && !typeToken.Type.IsType() {
-			return nil, fmt.Errorf("unexpected token %s at position %d, expected type identifier", typeToken.String(), typeToken.Pos)
+		var typeExpr TypeExpression
+		contentType, err := p.parseBinaryResponse()
+		if err != nil {
+			return nil, err
+		}
+		var stream string
+		if contentType == "" {
+			if stream, err = p.parseStreamFormat(); err != nil {
+				return nil, err
+			}
+			if stream != "" || !p.isResponseEnd() {
+				if typeExpr, err = p.parseTypeExpression(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		var headers []FieldDeclaration
+		if isHeadersKeyword(p.peekToken()) {
+			if headers, err = p.parseHeadersBlock(); err != nil {
+				return nil, err
+			}
 		}
 
 		code, err := strconv.Atoi(codeToken.Value)
@@ -254,8 +953,11 @@ func (p *Parser) parseResponseDeclarations() ([]ResponseDeclaration, error) {
 			return nil, fmt.Errorf("invalid response code %s at position %d", codeToken.Value, codeToken.Pos)
 		}
 		responses = append(responses, ResponseDeclaration{
-			Code: code,
-			Type: SimpleTypeExpression{Name: typeToken.Value},
+			Code:        code,
+			Type:        typeExpr,
+			ContentType: contentType,
+			Stream:      stream,
+			Headers:     headers,
 		})
 	}
 	if err := p.match(TokenCloseBrace); err != nil {
@@ -300,12 +1002,29 @@ func (p *Parser) parseEndpointBody() ([]EndpointFieldDeclaration, error) {
 		fields = append(fields, QueryDeclaration{Fields: fieldDecls})
 	}
 
+	if isHeadersKeyword(p.peekToken()) {
+		fieldDecls, err := p.parseHeadersBlock()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, HeadersDeclaration{Fields: fieldDecls})
+	}
+
+	if isCookiesKeyword(p.peekToken()) {
+		fieldDecls, err := p.parseHeadersBlock()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, CookiesDeclaration{Fields: fieldDecls})
+	}
+
 	token = p.peekToken()
 	if token.Type == TokenBody {
 		p.consumeToken()
-		typeToken := p.readToken()
-		if typeToken.Type != TokenIdentifier {
-			return nil, fmt.Errorf("unexpected token %s at position %d, expected type identifier", typeToken.String(), typeToken.Pos)
+		contentType := p.parseBodyContentType()
+		typeExpr, err := p.parseTypeExpression()
+		if err != nil {
+			return nil, err
 		}
 
 		optional := false
@@ -315,8 +1034,9 @@ func (p *Parser) parseEndpointBody() ([]EndpointFieldDeclaration, error) {
 		}
 
 		fields = append(fields, BodyDeclaration{
-			Type:     SimpleTypeExpression{Name: typeToken.Value},
-			Optional: optional,
+			Type:        typeExpr,
+			Optional:    optional,
+			ContentType: contentType,
 		})
 	}
 
@@ -332,10 +1052,70 @@ func (p *Parser) parseEndpointBody() ([]EndpointFieldDeclaration, error) {
 	return fields, nil
 }
 
-func (p *Parser) parseEndpointDeclarations() ([]EndpointDeclaration, error) {
-	endpointDecls := []EndpointDeclaration{}
-	token := p.peekToken()
-	for token.Type == TokenEndpoint {
+// parseChannelDeclaration parses a channel declaration, such as
+// channel /ws/orders OrderFeed { client OrderSubscribe server OrderEvent }.
+func (p *Parser) parseChannelDeclaration() (ChannelDeclaration, error) {
+	p.consumeToken()
+	pathToken := p.readToken()
+	if pathToken.Type != TokenPath {
+		return ChannelDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected path", pathToken.String(), pathToken.Pos)
+	}
+	nameToken := p.readToken()
+	if nameToken.Type != TokenIdentifier {
+		return ChannelDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected channel name", nameToken.String(), nameToken.Pos)
+	}
+	if err := p.match(TokenOpenBrace); err != nil {
+		return ChannelDeclaration{}, err
+	}
+	var messages [2]TypeExpression
+	for i, side := range []string{"client", "server"} {
+		tok := p.readToken()
+		if tok.Type != TokenIdentifier || tok.Value != side {
+			return ChannelDeclaration{}, fmt.Errorf("unexpected token %s at position %d, expected %s", tok.String(), tok.Pos, side)
+		}
+		typeExpr, err := p.parseTypeExpression()
+		if err != nil {
+			return ChannelDeclaration{}, err
+		}
+		messages[i] = typeExpr
+	}
+	if err := p.match(TokenCloseBrace); err != nil {
+		return ChannelDeclaration{}, err
+	}
+	return ChannelDeclaration{
+		Name:   nameToken.Value,
+		Path:   pathToken.Value,
+		Client: messages[0],
+		Server: messages[1],
+	}, nil
+}
+
+// parseEndpointDeclarations parses the endpoint and channel declarations
+// following the types of a spec.
+func (p *Parser) parseEndpointDeclarations() ([]Declaration, error) {
+	endpointDecls := []Declaration{}
+	for {
+		doc := p.parseDocComment()
+		annotations, err := p.parseAnnotations()
+		if err != nil {
+			return nil, err
+		}
+		if isChannelKeyword(p.peekToken()) {
+			channel, err := p.parseChannelDeclaration()
+			if err != nil {
+				return nil, err
+			}
+			channel.Doc, channel.Annotations = doc, annotations
+			endpointDecls = append(endpointDecls, channel)
+			continue
+		}
+		if p.peekToken().Type != TokenEndpoint {
+			if len(annotations) > 0 {
+				tok := p.peekToken()
+				return nil, fmt.Errorf("unexpected token %s at position %d, expected endpoint or channel", tok.String(), tok.Pos)
+			}
+			break
+		}
 		p.consumeToken()
 		methodToken := p.readToken()
 		if !methodToken.Type.IsHTTPMethod() {
@@ -364,47 +1144,70 @@ func (p *Parser) parseEndpointDeclarations() ([]EndpointDeclaration, error) {
 		}
 
 		endpointDecls = append(endpointDecls, EndpointDeclaration{
-			Name:   endpointNameToken.Value,
-			Method: methodToken.Value,
-			Path:   pathToken.Value,
-			Body:   body,
+			Doc:         doc,
+			Annotations: annotations,
+			Name:        endpointNameToken.Value,
+			Method:      methodToken.Value,
+			Path:        pathToken.Value,
+			Body:        body,
 		})
-		token = p.peekToken()
 	}
 	return endpointDecls, nil
 }
 
 func (p *Parser) parseSpec() (*Spec, error) {
-	if err := p.match(TokenAPI); err != nil {
-		return nil, err
-	}
-	nameToken := p.readToken()
-	if nameToken.Type != TokenIdentifier {
-		return nil, fmt.Errorf("unexpected token %s at position %d, expected identifier", nameToken.String(), nameToken.Pos)
+	var name string
+	start := p.pos
+	doc := p.parseDocComment()
+	if p.peekToken().Type == TokenAPI {
+		p.consumeToken()
+		nameToken := p.readToken()
+		if nameToken.Type != TokenIdentifier {
+			return nil, fmt.Errorf("unexpected token %s at position %d, expected identifier", nameToken.String(), nameToken.Pos)
+		}
+		name = nameToken.Value
+	} else {
+		// The comment documents the first declaration instead.
+		p.pos = start
+		doc = ""
 	}
 
-	decs := []Declaration{}
-	next := p.peekToken()
-	if next.Type == TokenType {
-		decl, err := p.parseTypeDeclarations()
-		if err != nil {
-			return nil, err
+	var pkg string
+	if isPackageKeyword(p.peekToken()) {
+		p.consumeToken()
+		pkgToken := p.readToken()
+		if pkgToken.Type != TokenIdentifier {
+			return nil, fmt.Errorf("unexpected token %s at position %d, expected package name", pkgToken.String(), pkgToken.Pos)
 		}
-		for _, td := range decl {
-			decs = append(decs, td)
+		pkg = pkgToken.Value
+	}
+
+	var imports []string
+	for isImportKeyword(p.peekToken()) {
+		p.consumeToken()
+		pathToken := p.readToken()
+		if pathToken.Type != TokenStringLiteral {
+			return nil, fmt.Errorf("unexpected token %s at position %d, expected import path", pathToken.String(), pathToken.Pos)
 		}
+		imports = append(imports, pathToken.Value)
 	}
 
-	endpoints, err := p.parseEndpointDeclarations()
+	decs, err := p.parseTypeDeclarations()
 	if err != nil {
 		return nil, err
 	}
-	for _, ed := range endpoints {
-		decs = append(decs, ed)
+
+	endpoints, err := p.parseEndpointDeclarations()
+	if err != nil {
+		return nil, err
 	}
+	decs = append(decs, endpoints...)
 
 	return &Spec{
-		Name:         nameToken.Value,
+		Doc:          doc,
+		Name:         name,
+		Package:      pkg,
+		Imports:      imports,
 		Declarations: decs,
 	}, nil
 }
@@ -414,6 +1217,9 @@ func (p *Parser) Parse() (*Spec, error) {
 	if err != nil {
 		return nil, err
 	}
+	if tok := p.peekToken(); tok.Type != TokenEOF {
+		return nil, fmt.Errorf("unexpected token %s at position %d, expected endpoint, channel or end of input", tok.String(), tok.Pos)
+	}
 	return spec, nil
 }
 
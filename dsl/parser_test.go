@@ -0,0 +1,117 @@
+package dsl_test
+
+import (
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+)
+
+func TestParseRejectsTrailingInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"garbage after types", `api Shop
+
+type Item {
+  id: integer
+}
+
+}`},
+		{"misspelled endpoint keyword", `api Shop
+
+type Item {
+  id: integer
+}
+
+endpont GET /items ListItems {
+  responses {
+    200 [Item]
+  }
+}`},
+		{"type after endpoints", `api Shop
+
+endpoint GET /items ListItems {
+  responses {
+    200 [string]
+  }
+}
+
+type Item {
+  id: integer
+}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := dsl.NewParserFromString(tt.input).Parse(); err == nil {
+				t.Errorf("expected error for trailing input")
+			}
+		})
+	}
+}
+
+func TestOnlyImportedFilesOmitAPI(t *testing.T) {
+	input := `type Item {
+  id: integer
+}`
+	if _, err := dsl.NewParserFromString(input).Parse(); err != nil {
+		t.Fatalf("expected a file without api to parse, got error: %v", err)
+	}
+	if _, err := dsl.NewTranslatorFromString(input); err == nil {
+		t.Errorf("expected error for root file without api")
+	}
+}
+
+func TestParseRejectsTrailingInputAfterHeader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"after package", `package shop extra`},
+		{"after package with types", `package shop
+
+shop.Item
+
+type Item {
+  id: integer
+}`},
+		{"after import", `package shop
+import "common.scapi" "other.scapi"`},
+		{"after import with types", `import "common.scapi"
+42
+
+type Item {
+  id: integer
+}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := dsl.NewParserFromString(tt.input).Parse(); err == nil {
+				t.Errorf("expected error for trailing input")
+			}
+		})
+	}
+}
+
+func TestParseRejectsMalformedAnnotations(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+	}{
+		{"missing name", `@(1)`},
+		{"unclosed arguments", `@cache(60`},
+		{"missing comma", `@cache(60 120)`},
+		{"leading comma", `@cache(, 60)`},
+		{"missing named value", `@cache(ttl=)`},
+		{"non-literal argument", `@cache({})`},
+		{"duplicate named argument", `@cache(ttl=60, ttl=120)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := "api Shop\n\n" + tt.annotation + "\ntype Item {\n  id: integer\n}"
+			if _, err := dsl.NewParserFromString(input).Parse(); err == nil {
+				t.Errorf("expected error for %s", tt.annotation)
+			}
+		})
+	}
+}
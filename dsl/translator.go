@@ -1,20 +1,373 @@
 package dsl
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/printchard/scapi/spec"
 )
 
-type Translator struct{}
+type Translator struct {
+	types    map[string]*spec.Type
+	channels []spec.Channel
+	// source is the file whose declarations are being translated, if known.
+	source string
+	// pkg is the package of the declarations being translated, if any.
+	pkg string
+	// declared holds the qualified names of the types declared in all files.
+	declared map[string]bool
+	// typeParams holds the type parameters of the generic type being
+	// translated, if any.
+	typeParams []string
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// declareType declares typ in the current package under name.
+func (t *Translator) declareType(name string, typ *spec.Type) error {
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("type name %s cannot be qualified", name)
+	}
+	name = spec.QualifiedName(t.pkg, name)
+	if existing, exists := t.types[name]; exists {
+		if existing.Source != "" && existing.Source != t.source {
+			return fmt.Errorf("duplicate type declaration: %s (also declared in %s)", name, existing.Source)
+		}
+		return fmt.Errorf("duplicate type declaration: %s", name)
+	}
+	typ.Source = t.source
+	t.types[name] = typ
+	return nil
+}
+
+// resolveName returns the qualified name of the type called name in the
+// current package. Unqualified names refer to the package's own types before
+// those declared outside any package.
+func (t *Translator) resolveName(name string) string {
+	qualified := spec.QualifiedName(t.pkg, name)
+	if !strings.Contains(name, ".") && t.declared[qualified] {
+		return qualified
+	}
+	return name
+}
 
-func getTypeName(te TypeExpression) string {
-	switch t := te.(type) {
+// getTypeRef translates a type expression into a reference. Inline object
+// types are hoisted into a top-level type called name, which inherits the
+// type parameters in scope.
+func (t *Translator) getTypeRef(te TypeExpression, name string) (spec.TypeRef, error) {
+	switch te := te.(type) {
 	case SimpleTypeExpression:
-		return t.Name
+		if slices.Contains(t.typeParams, te.Name) {
+			return spec.TypeRef{Name: te.Name, TypeParam: true}, nil
+		}
+		return spec.TypeRef{Name: t.resolveName(te.Name)}, nil
+	case GenericTypeExpression:
+		args := make([]spec.TypeRef, len(te.Args))
+		for i, arg := range te.Args {
+			var err error
+			if args[i], err = t.getTypeRef(arg, name); err != nil {
+				return spec.TypeRef{}, err
+			}
+		}
+		return spec.TypeRef{Name: t.resolveName(te.Name), Args: args}, nil
 	case ArrayTypeExpression:
-		return getTypeName(t.ElementType)
+		elem, err := t.getTypeRef(te.ElementType, name)
+		if err != nil {
+			return spec.TypeRef{}, err
+		}
+		return spec.TypeRef{Elem: &elem}, nil
+	case ObjectTypeExpression:
+		objType, err := t.newObjectType(name, te.Fields)
+		if err != nil {
+			return spec.TypeRef{}, err
+		}
+		if err := t.declareType(name, &spec.Type{Kind: spec.Object, ObjectType: objType}); err != nil {
+			return spec.TypeRef{}, err
+		}
+		ref := spec.TypeRef{Name: spec.QualifiedName(t.pkg, name)}
+		for _, param := range t.typeParams {
+			ref.Args = append(ref.Args, spec.TypeRef{Name: param, TypeParam: true})
+		}
+		return ref, nil
+	case MapTypeExpression:
+		return spec.TypeRef{}, fmt.Errorf("nested map type in %s is not supported", name)
 	default:
-		return "unknown"
+		return spec.TypeRef{}, fmt.Errorf("unknown type expression in %s", name)
+	}
+}
+
+func newConstraint(decl AnnotationDeclaration) (spec.Constraint, error) {
+	kind := spec.ConstraintKind(decl.Name)
+	if len(decl.Args) != 1 {
+		return spec.Constraint{}, fmt.Errorf("constraint @%s expects one argument", decl.Name)
+	}
+	switch kind {
+	case spec.Pattern:
+		if decl.Args[0].Type != TokenStringLiteral {
+			return spec.Constraint{}, fmt.Errorf("constraint @%s expects a string", decl.Name)
+		}
+	default:
+		if decl.Args[0].Type != TokenNumberLiteral {
+			return spec.Constraint{}, fmt.Errorf("constraint @%s expects a number", decl.Name)
+		}
+	}
+	return spec.Constraint{Kind: kind, Value: decl.Args[0].Value}, nil
+}
+
+func isConstraint(name string) bool {
+	switch spec.ConstraintKind(name) {
+	case spec.MinLength, spec.MaxLength, spec.Min, spec.Max, spec.Pattern, spec.MinItems, spec.MaxItems:
+		return true
+	}
+	return false
+}
+
+func newDeprecation(decl AnnotationDeclaration) (*spec.Deprecation, error) {
+	deprecation := &spec.Deprecation{}
+	switch len(decl.Args) {
+	case 0:
+	case 1:
+		if decl.Args[0].Type != TokenStringLiteral {
+			return nil, fmt.Errorf("@deprecated expects a string message")
+		}
+		deprecation.Message = decl.Args[0].Value
+	default:
+		return nil, fmt.Errorf("@deprecated expects at most one message")
+	}
+	for name, value := range decl.NamedArgs {
+		if name != "since" {
+			return nil, fmt.Errorf("unknown @deprecated argument %s", name)
+		}
+		if value.Type != TokenStringLiteral {
+			return nil, fmt.Errorf("@deprecated since expects a string")
+		}
+		deprecation.Since = value.Value
+	}
+	return deprecation, nil
+}
+
+// newAnnotations translates annotation declarations, rejecting an annotation
+// given twice. A @deprecated annotation is returned as a deprecation rather
+// than kept with the others. The annotations are nil if there are none.
+func newAnnotations(decls []AnnotationDeclaration) (spec.Annotations, *spec.Deprecation, error) {
+	var annotations spec.Annotations
+	var deprecation *spec.Deprecation
+	seen := make(map[string]bool)
+	for _, decl := range decls {
+		if seen[decl.Name] {
+			return nil, nil, fmt.Errorf("duplicate annotation @%s", decl.Name)
+		}
+		seen[decl.Name] = true
+		if decl.Name == "deprecated" {
+			var err error
+			if deprecation, err = newDeprecation(decl); err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+		annotation := spec.Annotation{}
+		for _, arg := range decl.Args {
+			annotation.Args = append(annotation.Args, *newValue(arg))
+		}
+		for name, arg := range decl.NamedArgs {
+			if annotation.Named == nil {
+				annotation.Named = make(map[string]spec.Value)
+			}
+			annotation.Named[name] = *newValue(arg)
+		}
+		if annotations == nil {
+			annotations = make(spec.Annotations)
+		}
+		annotations[decl.Name] = annotation
+	}
+	return annotations, deprecation, nil
+}
+
+func newValue(tok Lexeme) *spec.Value {
+	switch tok.Type {
+	case TokenNumberLiteral:
+		return &spec.Value{Kind: spec.NumberValue, Literal: tok.Value}
+	case TokenStringLiteral:
+		return &spec.Value{Kind: spec.StringValue, Literal: tok.Value}
+	default:
+		return &spec.Value{Kind: spec.SymbolValue, Literal: tok.Value}
+	}
+}
+
+// newField translates a field declaration. scope names the enclosing type and
+// is used to name inline object types declared by the field.
+func (t *Translator) newField(fieldDecl FieldDeclaration, scope string) (spec.Field, error) {
+	if strings.Contains(fieldDecl.Identifier, ".") {
+		return spec.Field{}, fmt.Errorf("field name %s.%s cannot be qualified", scope, fieldDecl.Identifier)
+	}
+	name := scope + "_" + capitalize(fieldDecl.Identifier)
+	field := spec.Field{
+		Optional:    fieldDecl.Optional,
+		Nullable:    fieldDecl.Nullable,
+		Doc:         fieldDecl.Doc,
+		Cardinality: spec.Single,
+	}
+	if fieldDecl.Default != nil {
+		field.Default = newValue(*fieldDecl.Default)
+	}
+	var annotationDecls []AnnotationDeclaration
+	for _, decl := range fieldDecl.Annotations {
+		if !isConstraint(decl.Name) {
+			annotationDecls = append(annotationDecls, decl)
+			continue
+		}
+		constraint, err := newConstraint(decl)
+		if err != nil {
+			return spec.Field{}, fmt.Errorf("%v on field %s.%s", err, scope, fieldDecl.Identifier)
+		}
+		field.Constraints = append(field.Constraints, constraint)
+	}
+	annotations, deprecation, err := newAnnotations(annotationDecls)
+	if err != nil {
+		return spec.Field{}, fmt.Errorf("%v on field %s.%s", err, scope, fieldDecl.Identifier)
+	}
+	field.Annotations, field.Deprecated = annotations, deprecation
+	switch te := fieldDecl.Type.(type) {
+	case ArrayTypeExpression:
+		field.Cardinality = spec.Multiple
+		field.Ref, err = t.getTypeRef(te.ElementType, name)
+	case MapTypeExpression:
+		field.Cardinality = spec.Map
+		field.Key = &spec.TypeRef{Name: t.resolveName(te.KeyType.Name)}
+		field.Ref, err = t.getTypeRef(te.ValueType, name)
+	default:
+		field.Ref, err = t.getTypeRef(te, name)
+	}
+	return field, err
+}
+
+// resolveFields computes the fields of the object type called name that come
+// from other types, either inherited from its parents or derived by a type
+// operator, after doing the same for the types it depends on.
+func (t *Translator) resolveFields(name string, visiting, resolved map[string]bool) error {
+	typ := t.types[name]
+	if resolved[name] || typ == nil || typ.Kind != spec.Object {
+		return nil
+	}
+	if visiting[name] {
+		return fmt.Errorf("type %s depends on itself", name)
+	}
+	visiting[name] = true
+
+	var err error
+	if typ.ObjectType.Derivation != nil {
+		err = t.deriveFields(name, typ.ObjectType, visiting, resolved)
+	} else {
+		err = t.inheritFields(name, typ.ObjectType, visiting, resolved)
+	}
+	if err != nil {
+		return err
+	}
+	resolved[name] = true
+	return nil
+}
+
+// baseObject resolves ref to an object type and returns its fields with ref's
+// type arguments substituted for the object's type parameters.
+func (t *Translator) baseObject(ref spec.TypeRef, visiting, resolved map[string]bool) (map[string]spec.Field, bool, error) {
+	base, ok := t.types[ref.Name]
+	if ref.TypeParam || ref.IsArray() || !ok || base.Kind != spec.Object {
+		return nil, false, nil
+	}
+	if err := t.resolveFields(ref.Name, visiting, resolved); err != nil {
+		return nil, false, err
 	}
+	fields := make(map[string]spec.Field, len(base.ObjectType.Fields))
+	for fieldName, field := range base.ObjectType.Fields {
+		field.Ref = field.Ref.Substitute(base.ObjectType.TypeParams, ref.Args)
+		fields[fieldName] = field
+	}
+	return fields, true, nil
+}
+
+func (t *Translator) inheritFields(name string, obj *spec.ObjectType, visiting, resolved map[string]bool) error {
+	inherited := make(map[string]spec.Field)
+	for _, parentRef := range obj.Parents {
+		parentFields, ok, err := t.baseObject(parentRef, visiting, resolved)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("type %s extends %s, which is not an object type", name, parentRef)
+		}
+		for fieldName, field := range parentFields {
+			if existing, ok := inherited[fieldName]; ok && !existing.SameType(field) {
+				return fmt.Errorf("type %s inherits conflicting types for field %s", name, fieldName)
+			}
+			inherited[fieldName] = field
+		}
+	}
+	for fieldName, field := range inherited {
+		if own, ok := obj.Fields[fieldName]; ok {
+			if !own.SameType(field) {
+				return fmt.Errorf("type %s field %s conflicts with an inherited field", name, fieldName)
+			}
+			continue
+		}
+		obj.Fields[fieldName] = field
+	}
+	return nil
+}
+
+func (t *Translator) deriveFields(name string, obj *spec.ObjectType, visiting, resolved map[string]bool) error {
+	derivation := obj.Derivation
+	baseFields, ok, err := t.baseObject(derivation.Base, visiting, resolved)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("type %s applies %s to %s, which is not an object type", name, derivation.Operator, derivation.Base)
+	}
+	for _, fieldName := range derivation.Fields {
+		if _, ok := baseFields[fieldName]; !ok {
+			return fmt.Errorf("type %s: %s has no field %s", name, derivation.Base, fieldName)
+		}
+	}
+	for fieldName, field := range baseFields {
+		switch derivation.Operator {
+		case spec.Pick:
+			if !slices.Contains(derivation.Fields, fieldName) {
+				continue
+			}
+		case spec.Omit:
+			if slices.Contains(derivation.Fields, fieldName) {
+				continue
+			}
+		case spec.Partial:
+			// Absent fields of a partial object are left unchanged, so they
+			// take no default.
+			field.Optional = true
+			field.Default = nil
+		}
+		obj.Fields[fieldName] = field
+	}
+	return nil
+}
+
+func (t *Translator) newObjectType(typeName string, fieldDecls []FieldDeclaration) (*spec.ObjectType, error) {
+	objType := &spec.ObjectType{
+		Fields:     make(map[string]spec.Field),
+		TypeParams: t.typeParams,
+	}
+	for _, fieldDecl := range fieldDecls {
+		field, err := t.newField(fieldDecl, typeName)
+		if err != nil {
+			return nil, err
+		}
+		objType.Fields[fieldDecl.Identifier] = field
+	}
+	return objType, nil
 }
 
 func stringToHTTPMethod(s string) spec.HTTPMethod {
@@ -34,40 +387,213 @@ func stringToHTTPMethod(s string) spec.HTTPMethod {
 }
 
 func (t *Translator) Translate(s *Spec) (*spec.APISpec, error) {
-	types := make(map[string]*spec.Type)
+	if len(s.Imports) > 0 {
+		return nil, fmt.Errorf("cannot resolve import %q without a file path", s.Imports[0])
+	}
+	return t.translateFiles([]sourceFile{{spec: s}})
+}
+
+// translateFiles merges the declarations of files into one API. The last
+// file is the root, which names the API.
+func (t *Translator) translateFiles(files []sourceFile) (*spec.APISpec, error) {
+	root := files[len(files)-1]
+	if root.spec.Name == "" {
+		return nil, inFile(root.path, fmt.Errorf("missing api declaration"))
+	}
+	t.types = make(map[string]*spec.Type)
+	t.channels = nil
+	t.declared = make(map[string]bool)
+	for _, file := range files {
+		for _, decl := range file.spec.Declarations {
+			if name := declaredName(decl); name != "" {
+				t.declared[spec.QualifiedName(file.spec.Package, name)] = true
+			}
+		}
+	}
 	endpoints := []spec.Endpoint{}
-	for _, decl := range s.Declarations {
+	for _, file := range files {
+		t.source = file.path
+		t.pkg = file.spec.Package
+		fileEndpoints, err := t.translateDeclarations(file.spec.Declarations)
+		if err != nil {
+			return nil, inFile(file.path, err)
+		}
+		endpoints = append(endpoints, fileEndpoints...)
+	}
+
+	visiting, resolved := make(map[string]bool), make(map[string]bool)
+	for name, typ := range t.types {
+		if err := t.resolveFields(name, visiting, resolved); err != nil {
+			return nil, inFile(typ.Source, err)
+		}
+	}
+	api, err := spec.NewAPISpecWithChannels(root.spec.Name, "localhost:8080", endpoints, t.channels, t.types)
+	if err != nil {
+		return nil, err
+	}
+	api.Doc = root.spec.Doc
+	return api, nil
+}
+
+// declaredName returns the name of the type decl declares, if any.
+func declaredName(decl Declaration) string {
+	switch d := decl.(type) {
+	case TypeDeclaration:
+		return d.Identifier
+	case DerivedTypeDeclaration:
+		return d.Identifier
+	case AliasDeclaration:
+		return d.Identifier
+	case EnumDeclaration:
+		return d.Identifier
+	case UnionDeclaration:
+		return d.Identifier
+	}
+	return ""
+}
+
+func (t *Translator) translateDeclarations(decls []Declaration) ([]spec.Endpoint, error) {
+	endpoints := []spec.Endpoint{}
+	for _, decl := range decls {
+		var err error
 		switch d := decl.(type) {
 		case TypeDeclaration:
-			objType := &spec.ObjectType{
-				Fields: make(map[string]spec.Field),
+			var objType *spec.ObjectType
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on type %s", err, d.Identifier)
+			}
+			t.typeParams = d.TypeParams
+			objType, err = t.newObjectType(d.Identifier, d.FieldDeclarations)
+			if err != nil {
+				return nil, err
 			}
-			for _, fieldDecl := range d.FieldDeclarations {
-				card := spec.Single
-				if _, ok := fieldDecl.Type.(ArrayTypeExpression); ok {
-					card = spec.Multiple
+			for _, parent := range d.Parents {
+				var parentRef spec.TypeRef
+				parentRef, err = t.getTypeRef(parent, d.Identifier)
+				if err != nil {
+					return nil, err
+				}
+				objType.Parents = append(objType.Parents, parentRef)
+			}
+			t.typeParams = nil
+			err = t.declareType(d.Identifier, &spec.Type{
+				Kind:        spec.Object,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				ObjectType:  objType,
+			})
+		case DerivedTypeDeclaration:
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on type %s", err, d.Identifier)
+			}
+			var baseRef spec.TypeRef
+			baseRef, err = t.getTypeRef(d.Base, d.Identifier)
+			if err != nil {
+				return nil, err
+			}
+			err = t.declareType(d.Identifier, &spec.Type{
+				Kind:        spec.Object,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				ObjectType: &spec.ObjectType{
+					Fields: make(map[string]spec.Field),
+					Derivation: &spec.Derivation{
+						Operator: spec.DerivationOperator(d.Operator),
+						Base:     baseRef,
+						Fields:   d.Fields,
+					},
+				},
+			})
+		case AliasDeclaration:
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on type %s", err, d.Identifier)
+			}
+			err = t.declareType(d.Identifier, &spec.Type{
+				Kind:        spec.Alias,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				AliasType:   &spec.AliasType{Target: spec.TypeRef{Name: t.resolveName(d.Type.Name)}},
+			})
+		case EnumDeclaration:
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on enum %s", err, d.Identifier)
+			}
+			enumType := &spec.EnumType{Members: d.Members}
+			for member, decls := range d.MemberAnnotations {
+				memberAnnotations, memberDeprecation, err := newAnnotations(decls)
+				if err != nil {
+					return nil, fmt.Errorf("%v on enum member %s.%s", err, d.Identifier, member)
+				}
+				if memberAnnotations != nil {
+					if enumType.MemberAnnotations == nil {
+						enumType.MemberAnnotations = make(map[string]spec.Annotations)
+					}
+					enumType.MemberAnnotations[member] = memberAnnotations
 				}
-				fieldTypeRef := spec.TypeRef{Name: getTypeName(fieldDecl.Type)}
-				field := spec.Field{
-					Ref:         fieldTypeRef,
-					Optional:    fieldDecl.Optional,
-					Nullable:    fieldDecl.Nullable,
-					Cardinality: card,
+				if memberDeprecation != nil {
+					if enumType.Deprecated == nil {
+						enumType.Deprecated = make(map[string]*spec.Deprecation)
+					}
+					enumType.Deprecated[member] = memberDeprecation
 				}
-				objType.Fields[fieldDecl.Identifier] = field
 			}
-			types[d.Identifier] = &spec.Type{
-				Kind:       spec.Object,
-				ObjectType: objType,
+			err = t.declareType(d.Identifier, &spec.Type{
+				Kind:        spec.Enum,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				EnumType:    enumType,
+			})
+		case UnionDeclaration:
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on union %s", err, d.Identifier)
+			}
+			variants := make([]spec.TypeRef, len(d.Variants))
+			for i, variant := range d.Variants {
+				variants[i] = spec.TypeRef{Name: t.resolveName(variant)}
 			}
+			err = t.declareType(d.Identifier, &spec.Type{
+				Kind:        spec.Union,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				UnionType: &spec.UnionType{
+					Variants:      variants,
+					Discriminator: d.Discriminator,
+				},
+			})
 		case EndpointDeclaration:
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on endpoint %s", err, d.Name)
+			}
 			endpoint := spec.Endpoint{
-				Name:   d.Name,
-				Method: stringToHTTPMethod(d.Method),
-				Path:   spec.NewPathTemplate(d.Path),
+				Source:      t.source,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				Name:        d.Name,
+				Method:      stringToHTTPMethod(d.Method),
+				Path:        spec.NewPathTemplate(d.Path),
 				Input: &spec.InputShape{
-					Params: make(map[string]spec.Field),
-					Query:  make(map[string]spec.Field),
+					Params:  make(map[string]spec.Field),
+					Query:   make(map[string]spec.Field),
+					Headers: make(map[string]spec.Field),
+					Cookies: make(map[string]spec.Field),
 				},
 				Responses: []spec.Response{},
 			}
@@ -76,48 +602,101 @@ func (t *Translator) Translate(s *Spec) (*spec.APISpec, error) {
 				switch fd := fieldDecl.(type) {
 				case ParamsDeclaration:
 					for _, paramField := range fd.Fields {
-						card := spec.Single
-						if _, ok := paramField.Type.(ArrayTypeExpression); ok {
-							card = spec.Multiple
-						}
-						fieldTypeRef := spec.TypeRef{Name: getTypeName(paramField.Type)}
-						field := spec.Field{
-							Ref:         fieldTypeRef,
-							Optional:    paramField.Optional,
-							Nullable:    paramField.Nullable,
-							Cardinality: card,
+						endpoint.Input.Params[paramField.Identifier], err = t.newField(paramField, d.Name+"Params")
+						if err != nil {
+							return nil, err
 						}
-						endpoint.Input.Params[paramField.Identifier] = field
 					}
 				case QueryDeclaration:
 					for _, queryField := range fd.Fields {
-						card := spec.Single
-						if _, ok := queryField.Type.(ArrayTypeExpression); ok {
-							card = spec.Multiple
+						endpoint.Input.Query[queryField.Identifier], err = t.newField(queryField, d.Name+"Query")
+						if err != nil {
+							return nil, err
 						}
-						fieldTypeRef := spec.TypeRef{Name: getTypeName(queryField.Type)}
-						field := spec.Field{
-							Ref:         fieldTypeRef,
-							Optional:    queryField.Optional,
-							Nullable:    queryField.Nullable,
-							Cardinality: card,
+					}
+				case HeadersDeclaration:
+					for _, headerField := range fd.Fields {
+						endpoint.Input.Headers[headerField.Identifier], err = t.newField(headerField, d.Name+"Headers")
+						if err != nil {
+							return nil, err
+						}
+					}
+				case CookiesDeclaration:
+					for _, cookieField := range fd.Fields {
+						endpoint.Input.Cookies[cookieField.Identifier], err = t.newField(cookieField, d.Name+"Cookies")
+						if err != nil {
+							return nil, err
 						}
-						endpoint.Input.Query[queryField.Identifier] = field
 					}
 				case BodyDeclaration:
-					fieldTypeRef := spec.TypeRef{Name: getTypeName(fd.Type)}
-					endpoint.Input.Body = &fieldTypeRef
+					bodyRef, err := t.getTypeRef(fd.Type, d.Name+"Body")
+					if err != nil {
+						return nil, err
+					}
+					endpoint.Input.Body = &bodyRef
+					endpoint.Input.ContentType = fd.ContentType
+					if endpoint.Input.ContentType == "" {
+						endpoint.Input.ContentType = spec.JSONContentType
+					}
 				case ResponseDeclaration:
+					var respRef *spec.TypeRef
+					if fd.Type != nil {
+						ref, err := t.getTypeRef(fd.Type, fmt.Sprintf("%s%dResponse", d.Name, fd.Code))
+						if err != nil {
+							return nil, err
+						}
+						respRef = &ref
+					}
+					var headers map[string]spec.Field
+					for _, headerField := range fd.Headers {
+						if headers == nil {
+							headers = make(map[string]spec.Field)
+						}
+						headers[headerField.Identifier], err = t.newField(headerField, fmt.Sprintf("%s%dResponseHeaders", d.Name, fd.Code))
+						if err != nil {
+							return nil, err
+						}
+					}
 					endpoint.Responses = append(endpoint.Responses, spec.Response{
-						Code: fd.Code,
-						Ref:  &spec.TypeRef{Name: getTypeName(fd.Type)},
+						Code:        fd.Code,
+						Ref:         respRef,
+						ContentType: fd.ContentType,
+						Stream:      spec.StreamFormat(fd.Stream),
+						Headers:     headers,
 					})
 				}
 			}
 			endpoints = append(endpoints, endpoint)
+		case ChannelDeclaration:
+			var annotations spec.Annotations
+			var deprecation *spec.Deprecation
+			if annotations, deprecation, err = newAnnotations(d.Annotations); err != nil {
+				return nil, fmt.Errorf("%v on channel %s", err, d.Name)
+			}
+			clientRef, err := t.getTypeRef(d.Client, d.Name+"ClientMessage")
+			if err != nil {
+				return nil, err
+			}
+			serverRef, err := t.getTypeRef(d.Server, d.Name+"ServerMessage")
+			if err != nil {
+				return nil, err
+			}
+			t.channels = append(t.channels, spec.Channel{
+				Source:      t.source,
+				Doc:         d.Doc,
+				Annotations: annotations,
+				Deprecated:  deprecation,
+				Name:        d.Name,
+				Path:        spec.NewPathTemplate(d.Path),
+				Client:      clientRef,
+				Server:      serverRef,
+			})
+		}
+		if err != nil {
+			return nil, err
 		}
 	}
-	return spec.NewAPISpec(s.Name, "localhost:8080", endpoints, types)
+	return endpoints, nil
 }
 
 func NewTranslatorFromString(input string) (*spec.APISpec, error) {
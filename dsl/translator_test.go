@@ -0,0 +1,355 @@
+package dsl_test
+
+import (
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+	"github.com/printchard/scapi/spec"
+)
+
+func TestPartialClearsDefaults(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Settings
+
+type Settings {
+  theme?: string = "light"
+  pageSize?: integer = 20
+  name: string
+}
+
+type SettingsPatch = Partial<Settings>`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+
+	settings := api.Types["Settings"].ObjectType
+	if settings.Fields["theme"].Default == nil || settings.Fields["pageSize"].Default == nil {
+		t.Errorf("expected Settings to keep its defaults")
+	}
+	patch := api.Types["SettingsPatch"].ObjectType
+	for name, field := range patch.Fields {
+		if !field.Optional {
+			t.Errorf("expected SettingsPatch field %s to be optional", name)
+		}
+		if field.Default != nil {
+			t.Errorf("expected SettingsPatch field %s to have no default, got %s", name, field.Default)
+		}
+	}
+}
+
+func TestDeclarationKeywordsNameFields(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Shop
+package shop
+
+enum Kind { enum union }
+
+type Item {
+  enum: Kind
+  union?: string
+  import: boolean
+  package: integer
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+
+	item, ok := api.Types["shop.Item"]
+	if !ok {
+		t.Fatalf("expected type shop.Item")
+	}
+	for _, name := range []string{"enum", "union", "import", "package"} {
+		if _, ok := item.ObjectType.Fields[name]; !ok {
+			t.Errorf("expected Item to have field %s", name)
+		}
+	}
+	if members := api.Types["shop.Kind"].EnumType.Members; len(members) != 2 {
+		t.Errorf("expected Kind to have members enum and union, got %v", members)
+	}
+}
+
+func TestMapTypes(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Prices
+
+type Price { amount: float }
+
+type Catalog {
+  prices: map<string, Price>
+  counts?: map<integer, integer>
+  owner: { Name: string }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+
+	fields := api.Types["Catalog"].ObjectType.Fields
+	if prices := fields["prices"]; prices.Cardinality != spec.Map || prices.Key.Name != "string" || prices.Ref.Name != "Price" {
+		t.Errorf("expected prices to map string to Price, got %+v", prices)
+	}
+	if counts := fields["counts"]; counts.Cardinality != spec.Map || !counts.Optional {
+		t.Errorf("expected counts to be an optional map, got %+v", counts)
+	}
+	// Braces declare an inline object even when its field name could be a key.
+	if owner := fields["owner"]; owner.Cardinality != spec.Single {
+		t.Errorf("expected owner to be an object, got %+v", owner)
+	}
+
+	if _, err := dsl.NewTranslatorFromString(`api Prices
+
+type Catalog { prices: [map<string, float>] }`); err == nil {
+		t.Errorf("expected error for map type outside a field type, got nil")
+	}
+}
+
+func TestBodylessResponses(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Users
+
+type User { id: uuid }
+
+endpoint POST /users createUser {
+  body User
+  responses {
+    201 headers { Location: url }
+    409
+  }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+
+	responses := api.Endpoints[0].Responses
+	if len(responses) != 2 {
+		t.Fatalf("expected 2 responses, got %d", len(responses))
+	}
+	for _, resp := range responses {
+		if !resp.IsEmpty() {
+			t.Errorf("expected response %d to have no body, got %v", resp.Code, resp.Ref)
+		}
+	}
+	if _, ok := responses[0].Headers["Location"]; !ok {
+		t.Errorf("expected response 201 to declare the Location header")
+	}
+}
+
+func TestNestedArrays(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Grid
+
+type Cell { value: integer }
+
+type Board {
+  cells: [[Cell]]
+}
+
+endpoint POST /boards createBoard {
+  body [[Cell]]
+  responses { 200 [[Board]] }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+
+	cells := api.Types["Board"].ObjectType.Fields["cells"]
+	if cells.Cardinality != spec.Multiple || cells.Ref.String() != "[Cell]" {
+		t.Errorf("expected cells to be a list of [Cell], got %+v", cells)
+	}
+	endpoint := api.Endpoints[0]
+	if body := endpoint.Input.Body; body.String() != "[[Cell]]" || body.Base().Name != "Cell" {
+		t.Errorf("expected body [[Cell]], got %s", body)
+	}
+	if ref := endpoint.Responses[0].Ref; ref.String() != "[[Board]]" {
+		t.Errorf("expected response [[Board]], got %s", ref)
+	}
+}
+
+func TestExtends(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   string
+		wantErr bool
+	}{
+		{"inheritance", `type Entity { id: string }
+type User extends Entity { name: string }`, false},
+		{"diamond", `type Entity { id: string }
+type Named extends Entity { name: string }
+type Priced extends Entity { price: float }
+type User extends Named, Priced { sku: string }`, false},
+		{"same type redeclaration", `type Entity { id: string }
+type User extends Entity { id: string }`, false},
+		{"conflicting redeclaration", `type Entity { id: string }
+type User extends Entity { id: integer }`, true},
+		{"conflicting parents", `type Named { id: string }
+type Numbered { id: integer }
+type User extends Named, Numbered { name: string }`, true},
+		{"parent cycle", `type Entity extends User { id: string }
+type User extends Entity { name: string }`, true},
+		{"self parent", `type User extends User { name: string }`, true},
+		{"non-object parent", `enum Role { admin }
+type User extends Role { name: string }`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, err := dsl.NewTranslatorFromString("api Users\n\n" + tt.types)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected valid spec, got error: %v", err)
+			}
+			user := api.Types["User"].ObjectType
+			if _, ok := user.Fields["id"]; !ok {
+				t.Errorf("expected User to inherit id, got %v", user.Fields)
+			}
+		})
+	}
+}
+
+func TestDerivedTypes(t *testing.T) {
+	const types = `type User {
+  id: string
+  name: string
+  email: string
+}
+
+type Page<T> {
+  items: [T]
+  total: integer
+}
+
+`
+	tests := []struct {
+		name string
+		decl string
+		// wantFields holds the fields of Derived, or is nil if the
+		// declaration is invalid.
+		wantFields map[string]string
+	}{
+		{"pick", `type Derived = Pick<User, id, name>`, map[string]string{"id": "string", "name": "string"}},
+		{"omit", `type Derived = Omit<User, email>`, map[string]string{"id": "string", "name": "string"}},
+		{"partial", `type Derived = Partial<User>`, map[string]string{"id": "string", "name": "string", "email": "string"}},
+		{"generic base", `type Derived = Pick<Page<User>, items>`, map[string]string{"items": "User"}},
+		{"derived base", `type Public = Omit<User, email>
+type Derived = Pick<Public, id>`, map[string]string{"id": "string"}},
+		{"unknown picked field", `type Derived = Pick<User, id, phone>`, nil},
+		{"unknown omitted field", `type Derived = Omit<User, phone>`, nil},
+		{"non-object base", `enum Role { admin }
+type Derived = Pick<Role, admin>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api, err := dsl.NewTranslatorFromString("api Users\n\n" + types + tt.decl)
+			if tt.wantFields == nil {
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected valid spec, got error: %v", err)
+			}
+			fields := api.Types["Derived"].ObjectType.Fields
+			if len(fields) != len(tt.wantFields) {
+				t.Errorf("expected fields %v, got %v", tt.wantFields, fields)
+			}
+			for name, ref := range tt.wantFields {
+				if field, ok := fields[name]; !ok || field.Ref.String() != ref {
+					t.Errorf("expected field %s of type %s, got %+v", name, ref, field)
+				}
+			}
+		})
+	}
+}
+
+func TestUnknownAnnotationsAreKept(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Shop
+
+@cache(60, "public", ttl=120)
+@internal
+type Item {
+  id: integer @column("item_id") @min(1)
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	item := api.Types["Item"]
+	cache, ok := item.Annotations["cache"]
+	if !ok || len(cache.Args) != 2 || cache.Args[0].Literal != "60" || cache.Args[1].Kind != spec.StringValue || cache.Named["ttl"].Literal != "120" {
+		t.Errorf("expected @cache(60, \"public\", ttl=120), got %+v", cache)
+	}
+	if _, ok := item.Annotations["internal"]; !ok {
+		t.Errorf("expected @internal to be kept, got %v", item.Annotations)
+	}
+	id := item.ObjectType.Fields["id"]
+	if _, ok := id.Annotations["column"]; !ok || len(id.Constraints) != 1 {
+		t.Errorf("expected @column to be kept apart from the @min constraint, got %+v", id)
+	}
+}
+
+func TestMalformedAnnotationArguments(t *testing.T) {
+	tests := []struct {
+		name       string
+		annotation string
+	}{
+		{"duplicate annotation", `@internal @internal`},
+		{"deprecated message not a string", `@deprecated(1)`},
+		{"deprecated with two messages", `@deprecated("a", "b")`},
+		{"deprecated unknown argument", `@deprecated(until="2.0")`},
+		{"deprecated since not a string", `@deprecated(since=2)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := dsl.NewTranslatorFromString("api Shop\n\n" + tt.annotation + "\ntype Item {\n  id: integer\n}")
+			if err == nil {
+				t.Errorf("expected error for %s", tt.annotation)
+			}
+		})
+	}
+	for _, constraint := range []string{`@min("1")`, `@min(1, 2)`, `@min`, `@pattern(1)`} {
+		if _, err := dsl.NewTranslatorFromString("api Shop\n\ntype Item {\n  id: integer " + constraint + "\n}"); err == nil {
+			t.Errorf("expected error for constraint %s", constraint)
+		}
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`/// The user service.
+api Users
+
+/// A registered user.
+///
+/// Users sign up with an email.
+@deprecated("use Account", since="2.0")
+type User {
+  /// The user's unique id.
+  /// Never reused.
+  id: uuid
+  /// Old login name.
+  login?: string @deprecated
+}
+
+/// Fetches a user.
+endpoint GET /users/{id} getUser {
+  params { id: uuid }
+  responses { 200 User }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+
+	if api.Doc != "The user service." {
+		t.Errorf("expected api doc, got %q", api.Doc)
+	}
+	user := api.Types["User"]
+	if want := "A registered user.\n\nUsers sign up with an email."; user.Doc != want || user.Deprecated == nil {
+		t.Errorf("expected User doc %q along with a deprecation, got %q, %v", want, user.Doc, user.Deprecated)
+	}
+	if id := user.ObjectType.Fields["id"]; id.Doc != "The user's unique id.\nNever reused." {
+		t.Errorf("expected multi-line id doc, got %q", id.Doc)
+	}
+	if login := user.ObjectType.Fields["login"]; login.Doc != "Old login name." || login.Deprecated == nil {
+		t.Errorf("expected login doc along with a deprecation, got %+v", login)
+	}
+	if doc := api.Endpoints[0].Doc; doc != "Fetches a user." {
+		t.Errorf("expected endpoint doc, got %q", doc)
+	}
+}
@@ -0,0 +1,333 @@
+package golang
+
+import (
+	"github.com/printchard/scapi/spec"
+)
+
+// generateChannelTypeDefs emits a minimal WebSocket implementation, as
+// specified by RFC 6455, and the Channel type wrapping it. They are shared
+// by clients and servers so that the imports they need are used either way.
+func (g *GoGenerator) generateChannelTypeDefs() string {
+	f := spec.NewFormatter()
+	checkErr := func(ret string) {
+		f.Line("if err != nil {")
+		f.Indent()
+		f.Line("return %s", ret)
+		f.Dedent()
+		f.Line("}")
+	}
+
+	f.Line("// Channel is a WebSocket connection exchanging JSON messages, sending")
+	f.Line("// messages of type S and receiving messages of type R.")
+	f.Line("type Channel[S, R any] struct {")
+	f.Indent()
+	f.Line("conn *wsConn")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Send sends message to the peer. It is safe to call concurrently with")
+	f.Line("// Receive.")
+	f.Line("func (c *Channel[S, R]) Send(message S) error {")
+	f.Indent()
+	f.Line("data, err := json.Marshal(message)")
+	checkErr("err")
+	f.Line("return c.conn.writeFrame(wsText, data)")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Receive waits for the next message from the peer. It returns io.EOF")
+	f.Line("// once the peer has closed the channel.")
+	f.Line("func (c *Channel[S, R]) Receive() (R, error) {")
+	f.Indent()
+	f.Line("var message R")
+	f.Line("data, err := c.conn.readMessage()")
+	checkErr("message, err")
+	f.Line("err = json.Unmarshal(data, &message)")
+	f.Line("return message, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Close closes the channel, telling the peer it is done.")
+	f.Line("func (c *Channel[S, R]) Close() error {")
+	f.Indent()
+	f.Line("// 1000 is the status code of a normal closure.")
+	f.Line("c.conn.writeFrame(wsClose, []byte{0x03, 0xe8})")
+	f.Line("return c.conn.rwc.Close()")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+
+	f.Line("// WebSocket opcodes.")
+	f.Line("const (")
+	f.Indent()
+	f.Line("wsText  = 0x1")
+	f.Line("wsClose = 0x8")
+	f.Line("wsPing  = 0x9")
+	f.Line("wsPong  = 0xa")
+	f.Dedent()
+	f.Line(")")
+	f.Line("")
+	f.Line("// wsMaxMessageSize bounds the size of the messages read from peers.")
+	f.Line("const wsMaxMessageSize = 32 << 20")
+	f.Line("")
+	f.Line("// wsConn is a WebSocket connection. Clients mask the frames they send.")
+	f.Line("type wsConn struct {")
+	f.Indent()
+	f.Line("rwc    io.ReadWriteCloser")
+	f.Line("reader *bufio.Reader")
+	f.Line("client bool")
+	f.Line("// mu serializes writes, which control frames answering reads may race.")
+	f.Line("mu sync.Mutex")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// wsAccept returns the Sec-WebSocket-Accept header answering the")
+	f.Line("// Sec-WebSocket-Key header key.")
+	f.Line("func wsAccept(key string) string {")
+	f.Indent()
+	f.Line(`sum := sha1.Sum([]byte(key + "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"))`)
+	f.Line("return base64.StdEncoding.EncodeToString(sum[:])")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// dialChannel opens a WebSocket connection to reqURL, an http or https URL.")
+	f.Line("func dialChannel[S, R any](ctx context.Context, reqURL string) (*Channel[S, R], error) {")
+	f.Indent()
+	f.Line(`req, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)`)
+	checkErr("nil, err")
+	f.Line("var nonce [16]byte")
+	f.Line("if _, err := rand.Read(nonce[:]); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("key := base64.StdEncoding.EncodeToString(nonce[:])")
+	f.Line(`req.Header.Set("Connection", "Upgrade")`)
+	f.Line(`req.Header.Set("Upgrade", "websocket")`)
+	f.Line(`req.Header.Set("Sec-WebSocket-Version", "13")`)
+	f.Line(`req.Header.Set("Sec-WebSocket-Key", key)`)
+	f.Line("response, err := http.DefaultClient.Do(req)")
+	checkErr("nil, err")
+	f.Line(`if response.StatusCode != http.StatusSwitchingProtocols || response.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {`)
+	f.Indent()
+	f.Line("response.Body.Close()")
+	f.Line(`return nil, fmt.Errorf("websocket handshake failed with status code: %%d", response.StatusCode)`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("// The body of a 101 response is the upgraded connection.")
+	f.Line("rwc := response.Body.(io.ReadWriteCloser)")
+	f.Line("return &Channel[S, R]{conn: &wsConn{rwc: rwc, reader: bufio.NewReader(rwc), client: true}}, nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// acceptChannel completes the WebSocket handshake of r and takes over its")
+	f.Line("// connection. Requests that are not handshakes are answered with an error.")
+	f.Line("func acceptChannel[S, R any](w http.ResponseWriter, r *http.Request) (*Channel[S, R], error) {")
+	f.Indent()
+	f.Line(`key := r.Header.Get("Sec-WebSocket-Key")`)
+	f.Line(`if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || key == "" {`)
+	f.Indent()
+	f.Line(`http.Error(w, "expected a WebSocket handshake", http.StatusBadRequest)`)
+	f.Line(`return nil, fmt.Errorf("expected a WebSocket handshake")`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("conn, rw, err := http.NewResponseController(w).Hijack()")
+	checkErr("nil, err")
+	f.Line(`fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %%s\r\n\r\n", wsAccept(key))`)
+	f.Line("if err := rw.Flush(); err != nil {")
+	f.Indent()
+	f.Line("conn.Close()")
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return &Channel[S, R]{conn: &wsConn{rwc: conn, reader: rw.Reader}}, nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// writeFrame writes payload as a single, final frame.")
+	f.Line("func (c *wsConn) writeFrame(opcode byte, payload []byte) error {")
+	f.Indent()
+	f.Line("c.mu.Lock()")
+	f.Line("defer c.mu.Unlock()")
+	f.Line("frame := []byte{0x80 | opcode, 0}")
+	f.Line("switch n := len(payload); {")
+	f.Line("case n < 126:")
+	f.Indent()
+	f.Line("frame[1] = byte(n)")
+	f.Dedent()
+	f.Line("case n <= 0xffff:")
+	f.Indent()
+	f.Line("frame[1] = 126")
+	f.Line("frame = binary.BigEndian.AppendUint16(frame, uint16(n))")
+	f.Dedent()
+	f.Line("default:")
+	f.Indent()
+	f.Line("frame[1] = 127")
+	f.Line("frame = binary.BigEndian.AppendUint64(frame, uint64(n))")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if !c.client {")
+	f.Indent()
+	f.Line("_, err := c.rwc.Write(append(frame, payload...))")
+	f.Line("return err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("var mask [4]byte")
+	f.Line("if _, err := rand.Read(mask[:]); err != nil {")
+	f.Indent()
+	f.Line("return err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("frame[1] |= 0x80")
+	f.Line("frame = append(frame, mask[:]...)")
+	f.Line("for i, b := range payload {")
+	f.Indent()
+	f.Line("frame = append(frame, b^mask[i%%4])")
+	f.Dedent()
+	f.Line("}")
+	f.Line("_, err := c.rwc.Write(frame)")
+	f.Line("return err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// readMessage reads the next data message, reassembling fragmented")
+	f.Line("// messages and answering pings and closes. It returns io.EOF once the")
+	f.Line("// peer has closed the connection.")
+	f.Line("func (c *wsConn) readMessage() ([]byte, error) {")
+	f.Indent()
+	f.Line("var message []byte")
+	f.Line("for {")
+	f.Indent()
+	f.Line("var header [2]byte")
+	f.Line("if _, err := io.ReadFull(c.reader, header[:]); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("final, opcode := header[0]&0x80 != 0, header[0]&0x0f")
+	f.Line("length := uint64(header[1] & 0x7f)")
+	f.Line("switch length {")
+	f.Line("case 126:")
+	f.Indent()
+	f.Line("var extended [2]byte")
+	f.Line("if _, err := io.ReadFull(c.reader, extended[:]); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("length = uint64(binary.BigEndian.Uint16(extended[:]))")
+	f.Dedent()
+	f.Line("case 127:")
+	f.Indent()
+	f.Line("var extended [8]byte")
+	f.Line("if _, err := io.ReadFull(c.reader, extended[:]); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("length = binary.BigEndian.Uint64(extended[:])")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if length > wsMaxMessageSize-uint64(len(message)) {")
+	f.Indent()
+	f.Line(`return nil, fmt.Errorf("websocket message exceeds %%d bytes", wsMaxMessageSize)`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("var mask [4]byte")
+	f.Line("masked := header[1]&0x80 != 0")
+	f.Line("if masked {")
+	f.Indent()
+	f.Line("if _, err := io.ReadFull(c.reader, mask[:]); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("payload := make([]byte, length)")
+	f.Line("if _, err := io.ReadFull(c.reader, payload); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if masked {")
+	f.Indent()
+	f.Line("for i := range payload {")
+	f.Indent()
+	f.Line("payload[i] ^= mask[i%%4]")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("switch opcode {")
+	f.Line("case wsClose:")
+	f.Indent()
+	f.Line("// Echo the status code, as the closing handshake requires.")
+	f.Line("c.writeFrame(wsClose, payload[:min(len(payload), 2)])")
+	f.Line("c.rwc.Close()")
+	f.Line("return nil, io.EOF")
+	f.Dedent()
+	f.Line("case wsPing:")
+	f.Indent()
+	f.Line("if err := c.writeFrame(wsPong, payload); err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("case wsPong:")
+	f.Line("default:")
+	f.Indent()
+	f.Line("message = append(message, payload...)")
+	f.Line("if final {")
+	f.Indent()
+	f.Line("return message, nil")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	return f.String()
+}
+
+// generateChannelDial emits the client method opening channel.
+func (g *GoGenerator) generateChannelDial(channel spec.Channel) string {
+	formatter := spec.NewFormatter()
+	generateDocComment(formatter, channel.Doc, channel.Deprecated)
+	channelType := g.channelType(channel.Client, channel.Server)
+	formatter.Line("func (c *Client) %s(ctx context.Context) (*%s, error) {", channel.Name, channelType)
+	formatter.Indent()
+	formatter.Line(`return dialChannel[%s](ctx, "%s%s")`, g.channelTypeArgs(channel.Client, channel.Server), g.API.BaseURL, channel.Path.String())
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// generateChannelAccept emits the function servers accept channel with.
+func (g *GoGenerator) generateChannelAccept(channel spec.Channel) string {
+	formatter := spec.NewFormatter()
+	channelType := g.channelType(channel.Server, channel.Client)
+	formatter.Line("// Accept%s completes the WebSocket handshake of the %s channel.", capitalize(channel.Name), channel.Name)
+	formatter.Line("func Accept%s(w http.ResponseWriter, r *http.Request) (*%s, error) {", capitalize(channel.Name), channelType)
+	formatter.Indent()
+	formatter.Line("return acceptChannel[%s](w, r)", g.channelTypeArgs(channel.Server, channel.Client))
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// channelType returns the Channel type of the side of a channel sending
+// messages of type send and receiving messages of type receive.
+func (g *GoGenerator) channelType(send, receive spec.TypeRef) string {
+	return "Channel[" + g.channelTypeArgs(send, receive) + "]"
+}
+
+func (g *GoGenerator) channelTypeArgs(send, receive spec.TypeRef) string {
+	return g.generateGoType(send, false) + ", " + g.generateGoType(receive, false)
+}
@@ -1,28 +1,242 @@
 package golang
 
 import (
+	"fmt"
+
 	"github.com/printchard/scapi/spec"
 )
 
+// stringifyValue returns a Go expression that formats expr, a value of the
+// primitive, alias or enum type ref, as a string for use in a path, query or
+// header.
+func (g *GoGenerator) stringifyValue(ref spec.TypeRef, expr string) string {
+	if g.Resolver.IsEnum(ref) {
+		return fmt.Sprintf("string(%s)", expr)
+	}
+	typ, _ := g.Resolver.PrimitiveOf(ref)
+	switch typ {
+	case spec.Boolean:
+		return fmt.Sprintf(`fmt.Sprintf("%%t", %s)`, expr)
+	case spec.Integer:
+		return fmt.Sprintf(`fmt.Sprintf("%%d", %s)`, expr)
+	case spec.Float:
+		return fmt.Sprintf(`fmt.Sprintf("%%f", %s)`, expr)
+	case spec.Int64:
+		return fmt.Sprintf(`fmt.Sprintf("%%d", %s)`, expr)
+	case spec.DateTime:
+		return fmt.Sprintf("%s.Format(time.RFC3339)", expr)
+	case spec.Date:
+		return fmt.Sprintf("%s.String()", expr)
+	case spec.Bytes:
+		return fmt.Sprintf("base64.StdEncoding.EncodeToString(%s)", expr)
+	default:
+		if g.Resolver.IsAlias(ref) {
+			return fmt.Sprintf("string(%s)", expr)
+		}
+		return expr
+	}
+}
+
 func (g *GoGenerator) generateQueryCreation(f *spec.Formatter, endpoint spec.Endpoint) {
-	f.Line("query := url.Values{}")
-	for name, field := range endpoint.Input.Query {
-		f.Line("if input.Query.%s {", capitalize(name))
+	g.generateValuesCreation(f, "query", "input.Query", endpoint.Input.Query)
+}
+
+// generateValuesCreation emits the url.Values called name holding fields,
+// which are read from the struct source.
+func (g *GoGenerator) generateValuesCreation(f *spec.Formatter, name, source string, fields map[string]spec.Field) {
+	f.Line("%s := url.Values{}", name)
+	for fieldName, field := range fields {
+		value := source + "." + capitalize(fieldName)
+		switch {
+		case field.Cardinality == spec.Multiple:
+			f.Line("for _, v := range %s {", value)
+			f.Indent()
+			f.Line(`%s.Add("%s", %s)`, name, fieldName, g.stringifyValue(field.Ref, "v"))
+			f.Dedent()
+			f.Line("}")
+		case field.Optional:
+			f.Line("if %s != nil {", value)
+			f.Indent()
+			f.Line(`%s.Add("%s", %s)`, name, fieldName, g.stringifyValue(field.Ref, g.fieldValue(field, value)))
+			f.Dedent()
+			f.Line("}")
+		default:
+			f.Line(`%s.Add("%s", %s)`, name, fieldName, g.stringifyValue(field.Ref, value))
+		}
+	}
+}
+
+// generateBodyCreation emits code encoding the request body of endpoint as
+// its content type declares, and returns the expression reading it.
+func (g *GoGenerator) generateBodyCreation(f *spec.Formatter, endpoint spec.Endpoint) string {
+	if endpoint.Input == nil || endpoint.Input.Body == nil {
+		return "nil"
+	}
+	switch endpoint.Input.Encoding() {
+	case spec.FormEncoding:
+		obj, _ := g.Resolver.ObjectOf(*endpoint.Input.Body)
+		g.generateValuesCreation(f, "form", "input.Body", obj.Fields)
+		return "strings.NewReader(form.Encode())"
+	case spec.MultipartEncoding:
+		g.generateMultipartBody(f, endpoint)
+		return "pr"
+	case spec.RawEncoding:
+		if primType, _ := g.Resolver.PrimitiveOf(*endpoint.Input.Body); primType == spec.Bytes {
+			return "bytes.NewReader(input.Body)"
+		}
+		return "strings.NewReader(string(input.Body))"
+	default:
+		f.Line("payload, err := json.Marshal(input.Body)")
+		f.Line("if err != nil {")
 		f.Indent()
-		typ, _ := g.Resolver.PrimitiveOf(field.Ref)
-		switch typ {
-		case spec.Boolean:
-			f.Line(`query.Add("%s", fmt.Sprintf("%%t", input.Query.%s))`, name, capitalize(name))
-		case spec.Integer:
-			f.Line(`query.Add("%s", fmt.Sprintf("%%d", input.Query.%s))`, name, capitalize(name))
-		case spec.Float:
-			f.Line(`query.Add("%s", fmt.Sprintf("%%f", input.Query.%s))`, name, capitalize(name))
-		case spec.String:
-			f.Line(`query.Add("%s", input.Query.%s)`, name, capitalize(name))
+		f.Line("return nil, err")
+		f.Dedent()
+		f.Line("}")
+		return "bytes.NewReader(payload)"
+	}
+}
+
+func (g *GoGenerator) generateHeaderCreation(f *spec.Formatter, endpoint spec.Endpoint) {
+	if endpoint.Input == nil {
+		return
+	}
+	for name, field := range endpoint.Input.Headers {
+		value := "input.Headers." + goFieldName(name)
+		if field.Optional {
+			f.Line("if %s != nil {", value)
+			f.Indent()
+			f.Line(`req.Header.Set("%s", %s)`, name, g.stringifyValue(field.Ref, g.fieldValue(field, value)))
+			f.Dedent()
+			f.Line("}")
+		} else {
+			f.Line(`req.Header.Set("%s", %s)`, name, g.stringifyValue(field.Ref, value))
 		}
+	}
+}
+
+func (g *GoGenerator) generateCookieCreation(f *spec.Formatter, endpoint spec.Endpoint) {
+	if endpoint.Input == nil {
+		return
+	}
+	for name, field := range endpoint.Input.Cookies {
+		value := "input.Cookies." + goFieldName(name)
+		if field.Optional {
+			f.Line("if %s != nil {", value)
+			f.Indent()
+			f.Line(`req.AddCookie(&http.Cookie{Name: "%s", Value: %s})`, name, g.stringifyValue(field.Ref, g.fieldValue(field, value)))
+			f.Dedent()
+			f.Line("}")
+		} else {
+			f.Line(`req.AddCookie(&http.Cookie{Name: "%s", Value: %s})`, name, g.stringifyValue(field.Ref, value))
+		}
+	}
+}
+
+// generateHeaderParsing emits code that parses the response header name
+// into target, a field of the type of field. A malformed header, or a
+// missing one unless it is optional, is returned as an error.
+func (g *GoGenerator) generateHeaderParsing(f *spec.Formatter, name string, field spec.Field, target string) {
+	f.Line(`if v := response.Header.Get("%s"); v != "" {`, name)
+	f.Indent()
+	g.generateValueParsing(f, field, target, "nil", name+" header")
+	f.Dedent()
+	if !field.Optional {
+		f.Line("} else {")
+		f.Indent()
+		f.Line(`return nil, fmt.Errorf("missing %s header")`, name)
+		f.Dedent()
+	}
+	f.Line("}")
+}
+
+// generateValueParsing emits code that parses the string v into target, a
+// field of the type of field. On a parse error, the emitted code returns
+// zero and an error describing what was being parsed.
+func (g *GoGenerator) generateValueParsing(f *spec.Formatter, field spec.Field, target, zero, what string) {
+	goType := g.generateGoType(field.Ref, false)
+	f.Line("var parsed %s", goType)
+	checkErr := func() {
+		f.Line("if err != nil {")
+		f.Indent()
+		f.Line(`return %s, fmt.Errorf("invalid %s: %%w", err)`, zero, what)
 		f.Dedent()
 		f.Line("}")
 	}
+	primType, _ := g.Resolver.PrimitiveOf(field.Ref)
+	switch primType {
+	case spec.Integer, spec.Int64, spec.Float, spec.Boolean:
+		f.Line("_, err := fmt.Sscan(v, &parsed)")
+		checkErr()
+	case spec.DateTime:
+		f.Line("t, err := time.Parse(time.RFC3339, v)")
+		checkErr()
+		f.Line("parsed = %s(t)", goType)
+	case spec.Date:
+		f.Line("t, err := time.Parse(time.DateOnly, v)")
+		checkErr()
+		f.Line("parsed = %s{Time: t}", goType)
+	case spec.Bytes:
+		f.Line("b, err := base64.StdEncoding.DecodeString(v)")
+		checkErr()
+		f.Line("parsed = %s(b)", goType)
+	default:
+		f.Line("parsed = %s(v)", goType)
+	}
+	switch {
+	case field.Cardinality == spec.Multiple:
+		f.Line("%s = append(%s, parsed)", target, target)
+	case g.isPointerField(field):
+		f.Line("%s = &parsed", target)
+	default:
+		f.Line("%s = parsed", target)
+	}
+}
+
+// generateMultipartBody emits code that streams the multipart body of
+// endpoint through a pipe, so files are not buffered in memory.
+func (g *GoGenerator) generateMultipartBody(f *spec.Formatter, endpoint spec.Endpoint) {
+	body, _ := g.Resolver.ObjectOf(*endpoint.Input.Body)
+	f.Line("pr, pw := io.Pipe()")
+	f.Line("mw := multipart.NewWriter(pw)")
+	f.Line("go func() {")
+	f.Indent()
+	f.Line("pw.CloseWithError(func() error {")
+	f.Indent()
+	for name, field := range body.Fields {
+		value := "input.Body." + capitalize(name)
+		write := func(expr string) {
+			if primType, _ := g.Resolver.PrimitiveOf(field.Ref); primType == spec.File {
+				f.Line(`if err := writeFile(mw, "%s", %s); err != nil {`, name, expr)
+			} else {
+				f.Line(`if err := mw.WriteField("%s", %s); err != nil {`, name, g.stringifyValue(field.Ref, expr))
+			}
+			f.Indent()
+			f.Line("return err")
+			f.Dedent()
+			f.Line("}")
+		}
+		switch {
+		case field.Cardinality == spec.Multiple:
+			f.Line("for _, v := range %s {", value)
+			f.Indent()
+			write("v")
+			f.Dedent()
+			f.Line("}")
+		case field.Optional:
+			f.Line("if %s != nil {", value)
+			f.Indent()
+			write(g.fieldValue(field, value))
+			f.Dedent()
+			f.Line("}")
+		default:
+			write(value)
+		}
+	}
+	f.Line("return mw.Close()")
+	f.Dedent()
+	f.Line("}())")
+	f.Dedent()
+	f.Line("}()")
 }
 
 func (g *GoGenerator) generatePathCreation(f *spec.Formatter, endpoint spec.Endpoint) {
@@ -33,25 +247,17 @@ func (g *GoGenerator) generatePathCreation(f *spec.Formatter, endpoint spec.Endp
 
 	f.Line("path := fmt.Sprintf(\"%s\",", endpoint.Path.FormatString())
 	f.Indent()
-	for paramName, field := range endpoint.Input.Params {
-		typ, _ := g.Resolver.PrimitiveOf(field.Ref)
-		switch typ {
-		case spec.String:
-			f.Line("input.Params.%s,", capitalize(paramName))
-		case spec.Integer:
-			f.Line("fmt.Sprintf(\"%%d\", input.Params.%s)", capitalize(paramName))
-		case spec.Float:
-			f.Line("fmt.Sprintf(\"%%f\", input.Params.%s)", capitalize(paramName))
-		case spec.Boolean:
-			f.Line("fmt.Sprintf(\"%%t\", input.Params.%s)", capitalize(paramName))
-		}
+	for _, paramName := range endpoint.Path.Params() {
+		field := endpoint.Input.Params[paramName]
+		f.Line("%s,", g.stringifyValue(field.Ref, "input.Params."+capitalize(paramName)))
 	}
 	f.Dedent()
 	f.Line(")")
 }
 
-func (g *GoGenerator) generateFetch(f *spec.Formatter, endpoint spec.Endpoint) {
-
+// generateRequest emits code creating req, the request of endpoint with
+// its path, query, body, headers and cookies.
+func (g *GoGenerator) generateRequest(f *spec.Formatter, endpoint spec.Endpoint) {
 	g.generateQueryCreation(f, endpoint)
 	g.generatePathCreation(f, endpoint)
 
@@ -60,54 +266,179 @@ func (g *GoGenerator) generateFetch(f *spec.Formatter, endpoint spec.Endpoint) {
 	} else {
 		f.Line(`reqURL := fmt.Sprintf("%s%%s", path)`, g.API.BaseURL)
 	}
-	f.Line(`response, err := http.Get(reqURL)`)
+	body := g.generateBodyCreation(f, endpoint)
+	f.Line(`req, err := http.NewRequestWithContext(ctx, "%s", reqURL, %s)`, endpoint.Method, body)
 	f.Line("if err != nil {")
 	f.Indent()
+	if endpoint.Input != nil && endpoint.Input.IsMultipart() {
+		// Unblock the writer goroutine, which nothing will read from.
+		f.Line("pr.CloseWithError(err)")
+	}
 	f.Line("return nil, err")
 	f.Dedent()
 	f.Line("}")
+	if endpoint.Input != nil && endpoint.Input.IsMultipart() {
+		f.Line(`req.Header.Set("Content-Type", mw.FormDataContentType())`)
+	} else if endpoint.Input != nil && endpoint.Input.Body != nil {
+		f.Line(`req.Header.Set("Content-Type", %q)`, endpoint.Input.ContentType)
+	}
+	g.generateHeaderCreation(f, endpoint)
+	g.generateCookieCreation(f, endpoint)
+}
 
-	f.Line("defer response.Body.Close()")
-	f.Line("decoder := json.NewDecoder(response.Body)")
+func (g *GoGenerator) generateFetch(f *spec.Formatter, endpoint spec.Endpoint) {
+	g.generateRequest(f, endpoint)
+	f.Line("response, err := http.DefaultClient.Do(req)")
+	f.Line("if err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
+	f.Line("}")
 
-	f.Line("switch response.StatusCode {")
 	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
+	if successResp.IsBinary() {
+		// A binary body is handed to the caller, who closes it, unless
+		// the request fails.
+		f.Line("streamed := false")
+		f.Line("defer func() {")
+		f.Indent()
+		f.Line("if !streamed {")
+		f.Indent()
+		f.Line("response.Body.Close()")
+		f.Dedent()
+		f.Line("}")
+		f.Dedent()
+		f.Line("}()")
+	} else {
+		f.Line("defer response.Body.Close()")
+	}
+	if decodesResponses(endpoint) {
+		f.Line("decoder := json.NewDecoder(response.Body)")
+	}
+
+	f.Line("switch response.StatusCode {")
 	f.Line("case %d:", successResp.Code)
 	f.Indent()
-	f.Line("var successResp %s", g.generateGoType(*successResp.Ref, false))
-	f.Line("if err := decoder.Decode(&successResp); err != nil {")
-	f.Indent()
-	f.Line("return nil, err")
+	switch {
+	case successResp.IsBinary():
+		f.Line(`successResp := Binary{ReadCloser: response.Body, ContentType: response.Header.Get("Content-Type"), ContentLength: response.ContentLength}`)
+	case successResp.IsEmpty():
+		// There is no body to read.
+	default:
+		f.Line("var successResp %s", g.generateGoType(*successResp.Ref, false))
+		f.Line("if err := decoder.Decode(&successResp); err != nil {")
+		f.Indent()
+		f.Line("return nil, err")
+		f.Dedent()
+		f.Line("}")
+	}
+	if len(successResp.Headers) > 0 {
+		f.Line("var headers %sResponseHeaders", endpoint.Name)
+		for name, field := range successResp.Headers {
+			g.generateHeaderParsing(f, name, field, "headers."+goFieldName(name))
+		}
+	}
+	if successResp.IsBinary() {
+		f.Line("streamed = true")
+	}
+	switch {
+	case len(successResp.Headers) > 0 && successResp.IsEmpty():
+		f.Line("return &%sResponse{Headers: headers}, nil", endpoint.Name)
+	case len(successResp.Headers) > 0:
+		f.Line("return &%sResponse{Body: successResp, Headers: headers}, nil", endpoint.Name)
+	case successResp.IsEmpty():
+		f.Line("return &struct{}{}, nil")
+	default:
+		f.Line("return &successResp, nil")
+	}
 	f.Dedent()
+	g.generateErrorCases(f, endpoint)
 	f.Line("}")
-	f.Line("return &successResp, nil")
-	f.Dedent()
+}
 
+// decodesResponses reports whether the client of endpoint decodes any of its
+// responses as JSON: those that are neither empty, binary nor streamed.
+func decodesResponses(endpoint spec.Endpoint) bool {
+	for _, resp := range endpoint.Responses {
+		if resp.Ref != nil && !resp.IsStream() {
+			return true
+		}
+	}
+	return false
+}
+
+// generateErrorCases emits the cases of a switch on the status code of a
+// response that return its error responses, and any unexpected status, as
+// errors.
+func (g *GoGenerator) generateErrorCases(f *spec.Formatter, endpoint spec.Endpoint) {
+	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
 	for _, resp := range endpoint.Responses {
 		if resp.Code == successResp.Code {
 			continue
 		}
 		f.Line("case %d:", resp.Code)
 		f.Indent()
+		if resp.IsEmpty() {
+			f.Line("return nil, &HTTPError{Code: %d, Header: response.Header}", resp.Code)
+			f.Dedent()
+			continue
+		}
 		f.Line("var errorResp %s", g.generateGoType(*resp.Ref, false))
 		f.Line("if err := decoder.Decode(&errorResp); err != nil {")
 		f.Indent()
 		f.Line("return nil, err")
 		f.Dedent()
 		f.Line("}")
-		f.Line("return nil, &HTTPError{Code: %d, Body: errorResp}", resp.Code)
+		f.Line("return nil, &HTTPError{Code: %d, Body: errorResp, Header: response.Header}", resp.Code)
 		f.Dedent()
 	}
 	f.Line("default:")
 	f.Indent()
 	f.Line("return nil, fmt.Errorf(\"unexpected status code: %%d\", response.StatusCode)")
 	f.Dedent()
+}
+
+// generateStreamFetch emits code opening the stream of events of endpoint.
+// The request is created again whenever the stream reconnects, with the ID
+// of the last event received.
+func (g *GoGenerator) generateStreamFetch(f *spec.Formatter, endpoint spec.Endpoint) {
+	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
+	f.Line("connect := func(lastEventID string) (*http.Response, error) {")
+	f.Indent()
+	g.generateRequest(f, endpoint)
+	f.Line(`req.Header.Set("Accept", %q)`, successResp.Stream.ContentType())
+	f.Line(`if lastEventID != "" {`)
+	f.Indent()
+	f.Line(`req.Header.Set("Last-Event-ID", lastEventID)`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("response, err := http.DefaultClient.Do(req)")
+	f.Line("if err != nil {")
+	f.Indent()
+	f.Line("return nil, err")
+	f.Dedent()
 	f.Line("}")
+	f.Line("if response.StatusCode == %d {", successResp.Code)
+	f.Indent()
+	f.Line("return response, nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("defer response.Body.Close()")
+	if decodesResponses(endpoint) {
+		f.Line("decoder := json.NewDecoder(response.Body)")
+	}
+	f.Line("switch response.StatusCode {")
+	g.generateErrorCases(f, endpoint)
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return openStream[%s](ctx, %t, connect)", g.generateGoType(*successResp.Ref, false), successResp.Stream == spec.SSE)
 }
 
 func (g *GoGenerator) generateClientMethod(endpoint spec.Endpoint) string {
 	defs := ""
 	formatter := spec.NewFormatter()
+	generateDocComment(formatter, endpoint.Doc, endpoint.Deprecated)
 	formatter.Partial("func (c *Client) %s(", endpoint.Name)
 	if endpoint.Input != nil {
 		formatter.Partial("ctx context.Context, ")
@@ -116,15 +447,19 @@ func (g *GoGenerator) generateClientMethod(endpoint spec.Endpoint) string {
 	} else {
 		formatter.Partial("ctx context.Context")
 	}
-	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
-	formatter.Partial(") (*%s, error) {\n", g.generateGoType(*successResp.Ref, false))
+	defs += g.generateResponseWrapper(endpoint)
+	formatter.Partial(") (*%s, error) {\n", g.resultType(endpoint))
 	formatter.Flush()
 	formatter.Indent()
 	if endpoint.Input != nil && endpoint.Input.Query != nil {
 		formatter.Line("") // keep a blank line after query creation if desired
 	}
-	g.generateFetch(formatter, endpoint)
-	formatter.Line("return nil, nil")
+	if g.Resolver.ResolveSuccessResponse(endpoint).IsStream() {
+		g.generateStreamFetch(formatter, endpoint)
+	} else {
+		g.generateFetch(formatter, endpoint)
+		formatter.Line("return nil, nil")
+	}
 	formatter.Dedent()
 	formatter.Line("}")
 
@@ -133,12 +468,44 @@ func (g *GoGenerator) generateClientMethod(endpoint spec.Endpoint) string {
 
 func (g *GoGenerator) GenerateClientMethods() string {
 	formatter := spec.NewFormatter()
+	generateDocComment(formatter, g.API.Doc, nil)
 	formatter.Line("type Client struct {}")
 	formatter.Line("")
+	if g.hasBinaryResponse() {
+		formatter.Line("// Binary is a binary response body, streamed from the connection as it")
+		formatter.Line("// is. Callers must close it. ContentLength is -1 if unknown.")
+		formatter.Line("type Binary struct {")
+		formatter.Indent()
+		formatter.Line("io.ReadCloser")
+		formatter.Line("ContentType   string")
+		formatter.Line("ContentLength int64")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("")
+	}
+	if g.hasMultipart() {
+		formatter.Line("// writeFile writes file as the part called field of a multipart body.")
+		formatter.Line("func writeFile(mw *multipart.Writer, field string, file File) error {")
+		formatter.Indent()
+		formatter.Line("part, err := mw.CreateFormFile(field, file.Name)")
+		formatter.Line("if err != nil {")
+		formatter.Indent()
+		formatter.Line("return err")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("_, err = io.Copy(part, file.Content)")
+		formatter.Line("return err")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("")
+	}
 	gen := ""
 	for _, endpoint := range g.API.Endpoints {
 		gen += g.generateClientMethod(endpoint)
 	}
+	for _, channel := range g.API.Channels {
+		gen += g.generateChannelDial(channel)
+	}
 
 	return formatter.String() + gen
 }
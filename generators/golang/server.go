@@ -4,6 +4,224 @@ import (
 	"github.com/printchard/scapi/spec"
 )
 
+// generateHeaderReader emits a function extracting the headers of endpoint
+// from an incoming request, converted to their declared types. Defaults are
+// applied to headers the request lacks. Nothing is emitted if the endpoint
+// declares no headers.
+func (g *GoGenerator) generateHeaderReader(endpoint spec.Endpoint) string {
+	if endpoint.Input == nil || len(endpoint.Input.Headers) == 0 {
+		return ""
+	}
+	formatter := spec.NewFormatter()
+	typeName := endpoint.Name + "Headers"
+	formatter.Line("// Read%s extracts the headers of %s from r.", capitalize(typeName), endpoint.Name)
+	formatter.Line("func Read%s(r *http.Request) (%s, error) {", capitalize(typeName), typeName)
+	formatter.Indent()
+	formatter.Line("var headers %s", typeName)
+	for name, field := range endpoint.Input.Headers {
+		formatter.Line(`if v := r.Header.Get("%s"); v != "" {`, name)
+		formatter.Indent()
+		g.generateValueParsing(formatter, field, "headers."+goFieldName(name), "headers", name+" header")
+		formatter.Dedent()
+		if !field.Optional {
+			formatter.Line("} else {")
+			formatter.Indent()
+			formatter.Line(`return headers, fmt.Errorf("missing %s header")`, name)
+			formatter.Dedent()
+		}
+		formatter.Line("}")
+	}
+	if hasDefaults(endpoint.Input.Headers) {
+		formatter.Line("headers.ApplyDefaults()")
+	}
+	formatter.Line("return headers, nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// generateQueryReader emits a function extracting the query parameters of
+// endpoint from an incoming request, converted to their declared types.
+// Defaults are applied to parameters the request lacks. Nothing is emitted if
+// the endpoint declares no query parameters.
+func (g *GoGenerator) generateQueryReader(endpoint spec.Endpoint) string {
+	if endpoint.Input == nil || len(endpoint.Input.Query) == 0 {
+		return ""
+	}
+	formatter := spec.NewFormatter()
+	typeName := endpoint.Name + "Query"
+	formatter.Line("// Read%s extracts the query parameters of %s from r.", capitalize(typeName), endpoint.Name)
+	formatter.Line("func Read%s(r *http.Request) (%s, error) {", capitalize(typeName), typeName)
+	formatter.Indent()
+	formatter.Line("var query %s", typeName)
+	formatter.Line("values := r.URL.Query()")
+	g.generateValuesParsing(formatter, endpoint.Input.Query, "values", "query", "query parameter")
+	formatter.Line("return query, nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// generateCookieReader emits a function extracting the cookies of endpoint
+// from an incoming request, converted to their declared types. Defaults are
+// applied to cookies the request lacks. Nothing is emitted if the endpoint
+// declares no cookies.
+func (g *GoGenerator) generateCookieReader(endpoint spec.Endpoint) string {
+	if endpoint.Input == nil || len(endpoint.Input.Cookies) == 0 {
+		return ""
+	}
+	formatter := spec.NewFormatter()
+	typeName := endpoint.Name + "Cookies"
+	formatter.Line("// Read%s extracts the cookies of %s from r.", capitalize(typeName), endpoint.Name)
+	formatter.Line("func Read%s(r *http.Request) (%s, error) {", capitalize(typeName), typeName)
+	formatter.Indent()
+	formatter.Line("var cookies %s", typeName)
+	hasDefaults := false
+	for name, field := range endpoint.Input.Cookies {
+		hasDefaults = hasDefaults || field.Default != nil
+		formatter.Line(`if c, err := r.Cookie("%s"); err == nil {`, name)
+		formatter.Indent()
+		formatter.Line("v := c.Value")
+		g.generateValueParsing(formatter, field, "cookies."+goFieldName(name), "cookies", name+" cookie")
+		formatter.Dedent()
+		if !field.Optional {
+			formatter.Line("} else {")
+			formatter.Indent()
+			formatter.Line(`return cookies, fmt.Errorf("missing %s cookie")`, name)
+			formatter.Dedent()
+		}
+		formatter.Line("}")
+	}
+	if hasDefaults {
+		formatter.Line("cookies.ApplyDefaults()")
+	}
+	formatter.Line("return cookies, nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// generateBodyReader emits a function decoding the body of endpoint from an
+// incoming request as its content type declares. Nothing is emitted for
+// endpoints without a body.
+func (g *GoGenerator) generateBodyReader(endpoint spec.Endpoint) string {
+	if endpoint.Input == nil || endpoint.Input.Body == nil {
+		return ""
+	}
+	bodyType := g.generateGoType(*endpoint.Input.Body, false)
+	formatter := spec.NewFormatter()
+	formatter.Line("// Read%sBody decodes the %s body of %s from r.", capitalize(endpoint.Name), endpoint.Input.ContentType, endpoint.Name)
+	formatter.Line("func Read%sBody(r *http.Request) (%s, error) {", capitalize(endpoint.Name), bodyType)
+	formatter.Indent()
+	formatter.Line("var body %s", bodyType)
+	checkErr := func() {
+		formatter.Line("if err != nil {")
+		formatter.Indent()
+		formatter.Line("return body, err")
+		formatter.Dedent()
+		formatter.Line("}")
+	}
+	switch endpoint.Input.Encoding() {
+	case spec.FormEncoding:
+		obj, _ := g.Resolver.ObjectOf(*endpoint.Input.Body)
+		formatter.Line("err := r.ParseForm()")
+		checkErr()
+		g.generateValuesParsing(formatter, obj.Fields, "r.PostForm", "body", "value")
+	case spec.MultipartEncoding:
+		obj, _ := g.Resolver.ObjectOf(*endpoint.Input.Body)
+		formatter.Line("// Parts beyond the first 32 MB are stored on disk.")
+		formatter.Line("err := r.ParseMultipartForm(32 << 20)")
+		checkErr()
+		g.generateValuesParsing(formatter, obj.Fields, "r.MultipartForm.Value", "body", "value")
+	case spec.RawEncoding:
+		formatter.Line("data, err := io.ReadAll(r.Body)")
+		checkErr()
+		formatter.Line("body = %s(data)", bodyType)
+	default:
+		formatter.Line("err := json.NewDecoder(r.Body).Decode(&body)")
+		checkErr()
+		if endpoint.Input.Body.IsArray() {
+			break
+		}
+		if obj, ok := g.Resolver.ObjectOf(*endpoint.Input.Body); ok && hasDefaults(obj.Fields) {
+			formatter.Line("body.ApplyDefaults()")
+		}
+	}
+	formatter.Line("return body, nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func hasDefaults(fields map[string]spec.Field) bool {
+	for _, field := range fields {
+		if field.Default != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// generateValuesParsing emits code that parses fields from the url.Values
+// called values into the struct called target, reporting errors about each
+// as a what. Files are read from a parsed multipart form.
+func (g *GoGenerator) generateValuesParsing(f *spec.Formatter, fields map[string]spec.Field, values, target, what string) {
+	for name, field := range fields {
+		fieldTarget := target + "." + capitalize(name)
+		source, value := values, "v"
+		if primType, _ := g.Resolver.PrimitiveOf(field.Ref); primType == spec.File {
+			source, value = "r.MultipartForm.File", "fh"
+		}
+		parse := func() {
+			if value == "v" {
+				g.generateValueParsing(f, field, fieldTarget, target, name+" "+what)
+				return
+			}
+			f.Line("file, err := openFile(fh)")
+			f.Line("if err != nil {")
+			f.Indent()
+			f.Line("return %s, err", target)
+			f.Dedent()
+			f.Line("}")
+			switch {
+			case field.Cardinality == spec.Multiple:
+				f.Line("%s = append(%s, file)", fieldTarget, fieldTarget)
+			case field.Optional:
+				f.Line("%s = &file", fieldTarget)
+			default:
+				f.Line("%s = file", fieldTarget)
+			}
+		}
+		if field.Cardinality == spec.Multiple {
+			f.Line(`for _, %s := range %s["%s"] {`, value, source, name)
+			f.Indent()
+			parse()
+			f.Dedent()
+			f.Line("}")
+			continue
+		}
+		f.Line(`if parts := %s["%s"]; len(parts) > 0 {`, source, name)
+		f.Indent()
+		f.Line("%s := parts[0]", value)
+		parse()
+		f.Dedent()
+		if !field.Optional {
+			f.Line("} else {")
+			f.Indent()
+			f.Line(`return %s, fmt.Errorf("missing %s %s")`, target, name, what)
+			f.Dedent()
+		}
+		f.Line("}")
+	}
+	if hasDefaults(fields) {
+		f.Line("%s.ApplyDefaults()", target)
+	}
+}
+
 func (g *GoGenerator) GenerateEndpointFunc(endpoint spec.Endpoint) (string, string) {
 	defs := ""
 	formatter := spec.NewFormatter()
@@ -11,12 +229,21 @@ func (g *GoGenerator) GenerateEndpointFunc(endpoint spec.Endpoint) (string, stri
 	if endpoint.Input != nil {
 		formatter.Partial("ctx context.Context, ")
 		defs += g.generateInputWrapper(endpoint)
+		defs += g.generateQueryReader(endpoint)
+		defs += g.generateHeaderReader(endpoint)
+		defs += g.generateCookieReader(endpoint)
+		defs += g.generateBodyReader(endpoint)
 		formatter.Partial("input %sInput", endpoint.Name)
 	} else {
 		formatter.Partial("ctx context.Context")
 	}
-	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
-	formatter.Partial(") (*%s, error)", successResp.Ref.Name)
+	defs += g.generateResponseWrapper(endpoint)
+	// Streamed responses are sent through a sink as the handler runs.
+	if successResp := g.Resolver.ResolveSuccessResponse(endpoint); successResp.IsStream() {
+		formatter.Partial(", events EventSink[%s]) error", g.generateGoType(*successResp.Ref, false))
+	} else {
+		formatter.Partial(") (*%s, error)", g.resultType(endpoint))
+	}
 	formatter.Flush()
 
 	return defs, formatter.String()
@@ -24,14 +251,50 @@ func (g *GoGenerator) GenerateEndpointFunc(endpoint spec.Endpoint) (string, stri
 
 func (g *GoGenerator) GenerateEndpoints() string {
 	formatter := spec.NewFormatter()
+	if g.hasBinaryResponse() {
+		formatter.Line("// Binary is a binary response body, streamed to the client as it is.")
+		formatter.Line("// ContentLength is -1 if unknown.")
+		formatter.Line("type Binary struct {")
+		formatter.Indent()
+		formatter.Line("io.Reader")
+		formatter.Line("ContentType   string")
+		formatter.Line("ContentLength int64")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("")
+	}
+	if g.hasMultipart() {
+		formatter.Line("// openFile opens a file uploaded in a multipart body. Its content is")
+		formatter.Line("// released once the request has been handled.")
+		formatter.Line("func openFile(fh *multipart.FileHeader) (File, error) {")
+		formatter.Indent()
+		formatter.Line("content, err := fh.Open()")
+		formatter.Line("if err != nil {")
+		formatter.Indent()
+		formatter.Line("return File{}, err")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("return File{Name: fh.Filename, Content: content}, nil")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("")
+	}
+	generateDocComment(formatter, g.API.Doc, nil)
 	formatter.Line("type %sServer interface {", g.API.Name)
 	formatter.Indent()
 	defs := ""
 	for _, endpoint := range g.API.Endpoints {
 		def, funcStr := g.GenerateEndpointFunc(endpoint)
 		defs += def
+		generateDocComment(formatter, endpoint.Doc, endpoint.Deprecated)
 		formatter.Line("%s", funcStr)
 	}
+	// Channels are served on the connection Accept<Channel> takes over.
+	for _, channel := range g.API.Channels {
+		defs += g.generateChannelAccept(channel)
+		generateDocComment(formatter, channel.Doc, channel.Deprecated)
+		formatter.Line("%s(ctx context.Context, conn *%s) error", capitalize(channel.Name), g.channelType(channel.Server, channel.Client))
+	}
 	formatter.Dedent()
 	formatter.Line("}")
 	return defs + formatter.String()
@@ -0,0 +1,431 @@
+package golang_test
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+	"github.com/printchard/scapi/generators/golang"
+	"github.com/printchard/scapi/spec"
+)
+
+// typeCheck parses and type-checks generated Go code, which reports unused
+// imports as errors, as the compiler does.
+func typeCheck(t *testing.T, code string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", code, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v\n%s", err, code)
+	}
+	conf := types.Config{Importer: importer.Default()}
+	if _, err := conf.Check("main", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatalf("generated code does not compile: %v\n%s", err, code)
+	}
+}
+
+func TestServerCompilesWithJSONBody(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Users
+
+type CreateUser { name: string }
+type User { id: uuid  name: string }
+
+endpoint POST /users createUser {
+  body CreateUser
+  responses { 201 User }
+}
+
+endpoint POST /users/batch createUsers {
+  body [CreateUser]
+  responses { 201 [User] }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	gen := golang.NewGoGenerator(api)
+	gen.Target = golang.ServerTarget
+	typeCheck(t, gen.GenerateTypeDefs()+gen.GenerateEndpoints())
+}
+
+func TestServerReadsHeaders(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Search
+
+type Result { id: uuid }
+
+endpoint GET /search search {
+  headers {
+    "X-Count": integer
+    "X-Signature"?: bytes
+    "X-Since"?: datetime
+    "X-Mode"?: string = "fast"
+  }
+  responses { 200 [Result] }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	gen := golang.NewGoGenerator(api)
+	gen.Target = golang.ServerTarget
+	code := gen.GenerateTypeDefs() + gen.GenerateEndpoints()
+	typeCheck(t, code)
+	for _, want := range []string{
+		"func ReadSearchHeaders(r *http.Request) (searchHeaders, error)",
+		`return headers, fmt.Errorf("missing X-Count header")`,
+		"headers.ApplyDefaults()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated server to contain %q", want)
+		}
+	}
+}
+
+func TestServerReadsQuery(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Users
+
+type User { id: uuid }
+
+endpoint GET /users listUsers {
+  query {
+    limit?: integer = 20
+    tags: [string]
+    q?: string
+  }
+  responses { 200 [User] }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	gen := golang.NewGoGenerator(api)
+	gen.Target = golang.ServerTarget
+	code := gen.GenerateTypeDefs() + gen.GenerateEndpoints()
+	typeCheck(t, code)
+	for _, want := range []string{
+		"func ReadListUsersQuery(r *http.Request) (listUsersQuery, error)",
+		"query.ApplyDefaults()",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated server to contain %q", want)
+		}
+	}
+}
+
+func TestBodylessResponsesCompile(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Users
+
+type User { id: uuid }
+
+endpoint POST /users createUser {
+  body User
+  responses {
+    201 headers { Location: url }
+    409
+  }
+}
+
+endpoint DELETE /users/{id} deleteUser {
+  params { id: uuid }
+  responses { 204 }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	server := golang.NewGoGenerator(api)
+	server.Target = golang.ServerTarget
+	typeCheck(t, server.GenerateTypeDefs()+server.GenerateEndpoints())
+	client := golang.NewGoGenerator(api)
+	typeCheck(t, client.GenerateTypeDefs()+client.GenerateClientMethods())
+}
+
+func TestCompilesWithoutEndpoints(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Shapes
+
+type Shape { name: string }`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	server := golang.NewGoGenerator(api)
+	server.Target = golang.ServerTarget
+	typeCheck(t, server.GenerateTypeDefs()+server.GenerateEndpoints())
+	client := golang.NewGoGenerator(api)
+	typeCheck(t, client.GenerateTypeDefs()+client.GenerateClientMethods())
+}
+
+func TestMultipartClientClosesPipeOnRequestError(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Files
+
+type Upload {
+  name: string
+  file: file
+}
+
+endpoint POST /files upload {
+  body multipart Upload
+  responses { 204 }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs() + client.GenerateClientMethods()
+	typeCheck(t, code)
+	if !strings.Contains(code, "pr.CloseWithError(err)") {
+		t.Errorf("expected the pipe to be closed when the request cannot be created:\n%s", code)
+	}
+}
+
+func TestReservedNamesCollide(t *testing.T) {
+	for _, name := range golang.ReservedNames {
+		t.Run(name, func(t *testing.T) {
+			api, err := dsl.NewTranslatorFromString(`api Shop
+
+type ` + name + ` { id: string }`)
+			if err != nil {
+				t.Fatalf("expected valid spec, got error: %v", err)
+			}
+			err = api.CheckNaming(spec.Naming{Reserved: golang.ReservedNames})
+			if err == nil || !strings.Contains(err.Error(), "helper type "+name) {
+				t.Errorf("expected %s to collide with the helper type, got %v", name, err)
+			}
+
+			api, err = dsl.NewTranslatorFromString(`api Shop
+package shop
+
+type ` + name + ` { id: string }`)
+			if err != nil {
+				t.Fatalf("expected valid spec, got error: %v", err)
+			}
+			if err := api.CheckNaming(spec.Naming{Reserved: golang.ReservedNames}); err != nil {
+				t.Errorf("expected shop.%s not to collide, got %v", name, err)
+			}
+		})
+	}
+}
+
+func TestInt64EncodesAsString(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Ledger
+
+type Id = int64
+
+type Entry {
+  id: Id
+  amount: int64 @min(0)
+  parts: [int64]
+  totals: map<int64, int64>
+  previous?: int64
+}
+
+endpoint GET /entries/{id} getEntry {
+  params { id: int64 }
+  query { after?: int64 = 5 }
+  responses {
+    200 Entry headers { "X-Total": int64 }
+  }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	server := golang.NewGoGenerator(api)
+	server.Target = golang.ServerTarget
+	typeCheck(t, server.GenerateTypeDefs()+server.GenerateEndpoints())
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs() + client.GenerateClientMethods()
+	typeCheck(t, code)
+	if !strings.Contains(code, "json.Marshal(strconv.FormatInt(int64(n), 10))") {
+		t.Errorf("expected Int64 to encode as a string:\n%s", code)
+	}
+}
+
+func TestRichPrimitivesCompile(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Events
+
+type Event {
+  id: uuid
+  at: datetime
+  day: date
+  payload: bytes
+  count: int64
+  price: decimal
+  contact: email
+  link: url
+  days?: [date]
+}
+
+endpoint GET /events/{id} getEvent {
+  params { id: uuid }
+  query {
+    since?: datetime
+    day?: date
+    token?: bytes
+  }
+  headers { "X-Contact": email }
+  responses {
+    200 Event headers { Location: url }
+  }
+}
+
+endpoint POST /events createEvent {
+  body Event
+  responses { 201 Event }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	server := golang.NewGoGenerator(api)
+	server.Target = golang.ServerTarget
+	typeCheck(t, server.GenerateTypeDefs()+server.GenerateEndpoints())
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs() + client.GenerateClientMethods()
+	typeCheck(t, code)
+	for _, want := range []string{
+		"Id string `json:\"id\"`",
+		"At time.Time `json:\"at\"`",
+		"Day Date `json:\"day\"`",
+		"Payload []byte `json:\"payload\"`",
+		"Price string `json:\"price\"`",
+		"Days []Date `json:\"days,omitempty\"`",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestUnionVariantsAreTaggedByLocalName(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Payments
+package billing
+
+type Card {
+  kind: string
+  number: string
+}
+
+type Transfer {
+  kind: string
+  iban: string
+}
+
+union Payment = Card | Transfer by kind`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs()
+	typeCheck(t, code+client.GenerateClientMethods())
+	for _, want := range []string{`v.Kind = "Card"`, `case "Transfer":`} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestNestedArraysCompile(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Grid
+
+type Cell { value: integer }
+
+type Board {
+  cells: [[Cell]]
+  tags?: [[[string]]]
+}
+
+endpoint POST /boards createBoard {
+  body [[Cell]]
+  responses { 200 [[Board]] }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	server := golang.NewGoGenerator(api)
+	server.Target = golang.ServerTarget
+	typeCheck(t, server.GenerateTypeDefs()+server.GenerateEndpoints())
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs() + client.GenerateClientMethods()
+	typeCheck(t, code)
+	if !strings.Contains(code, "Cells [][]Cell `json:\"cells\"`") {
+		t.Errorf("expected cells to be a [][]Cell:\n%s", code)
+	}
+}
+
+func TestDiamondCompiles(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Shop
+
+type Entity { id: string }
+
+type Named extends Entity { name: string }
+
+type Priced extends Entity { price: float }
+
+type Product extends Named, Priced {
+  sku: string
+}
+
+endpoint GET /products/{id} getProduct {
+  params { id: string }
+  responses { 200 Product }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	server := golang.NewGoGenerator(api)
+	server.Target = golang.ServerTarget
+	typeCheck(t, server.GenerateTypeDefs()+server.GenerateEndpoints())
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs() + client.GenerateClientMethods()
+	typeCheck(t, code)
+
+	// encoding/json ignores the id promoted from both parents, so Product
+	// redeclares it, while the fields of a single parent are promoted.
+	product := code[strings.Index(code, "type Product struct {"):]
+	product = product[:strings.Index(product, "}")]
+	if !strings.Contains(product, "Id string `json:\"id\"`") {
+		t.Errorf("expected Product to redeclare id:\n%s", product)
+	}
+	if strings.Contains(product, "Name string") || strings.Contains(product, "Price float64") {
+		t.Errorf("expected Product not to redeclare fields of a single parent:\n%s", product)
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`/// The user service.
+api Users
+
+/// A registered user.
+///
+/// Users sign up with an email.
+@deprecated("use Account", since="2.0")
+type User {
+  /// The user's unique id.
+  /// Never reused.
+  id: uuid
+  /// Old login name.
+  login?: string @deprecated
+}
+
+/// Fetches a user.
+endpoint GET /users/{id} getUser {
+  params { id: uuid }
+  responses { 200 User }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	client := golang.NewGoGenerator(api)
+	code := client.GenerateTypeDefs() + client.GenerateClientMethods()
+	typeCheck(t, code)
+	for _, want := range []string{
+		"// A registered user.\n//\n// Users sign up with an email.\n//\n// Deprecated: use Account (since 2.0)\ntype User struct {",
+		"  // The user's unique id.\n  // Never reused.\n  Id string",
+		"  // Old login name.\n  //\n  // Deprecated: do not use\n  Login *string",
+		"// The user service.\ntype Client struct",
+		"// Fetches a user.\nfunc (c *Client) getUser(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, code)
+		}
+	}
+}
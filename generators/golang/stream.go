@@ -0,0 +1,304 @@
+package golang
+
+import (
+	"github.com/printchard/scapi/spec"
+)
+
+// hasStreamResponse reports whether any endpoint streams its success
+// response.
+func (g *GoGenerator) hasStreamResponse() bool {
+	for _, endpoint := range g.API.Endpoints {
+		if g.Resolver.ResolveSuccessResponse(endpoint).IsStream() {
+			return true
+		}
+	}
+	return false
+}
+
+// generateStreamTypeDefs emits the types reading streamed responses in
+// clients and writing them in servers. They are shared by both so that the
+// imports they need are used either way.
+func (g *GoGenerator) generateStreamTypeDefs() string {
+	f := spec.NewFormatter()
+	checkErr := func(ret string) {
+		f.Line("if err != nil {")
+		f.Indent()
+		f.Line("return %s", ret)
+		f.Dedent()
+		f.Line("}")
+	}
+
+	f.Line("// Stream reads the events of type T of a streamed response. Server-sent")
+	f.Line("// event streams reconnect when their connection drops, resuming after the")
+	f.Line("// last event received.")
+	f.Line("type Stream[T any] struct {")
+	f.Indent()
+	f.Line("ctx     context.Context")
+	f.Line("connect func(lastEventID string) (*http.Response, error)")
+	f.Line("sse     bool")
+	f.Line("body    io.ReadCloser")
+	f.Line("reader  *bufio.Reader")
+	f.Line("lastID  string")
+	f.Line("retry   time.Duration")
+	f.Line("event   T")
+	f.Line("err     error")
+	f.Line("done    bool")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// openStream opens a stream of events read from the responses connect")
+	f.Line("// returns. Reconnections pass it the ID of the last event received.")
+	f.Line("func openStream[T any](ctx context.Context, sse bool, connect func(lastEventID string) (*http.Response, error)) (*Stream[T], error) {")
+	f.Indent()
+	f.Line(`response, err := connect("")`)
+	checkErr("nil, err")
+	f.Line("return &Stream[T]{")
+	f.Indent()
+	f.Line("ctx:     ctx,")
+	f.Line("connect: connect,")
+	f.Line("sse:     sse,")
+	f.Line("body:    response.Body,")
+	f.Line("reader:  bufio.NewReader(response.Body),")
+	f.Line("retry:   3 * time.Second,")
+	f.Dedent()
+	f.Line("}, nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Next reads the next event, which Event then returns. It returns false")
+	f.Line("// once the stream has ended, failed or been closed, or its context is")
+	f.Line("// done; Err then returns the error that stopped it, if any.")
+	f.Line("func (s *Stream[T]) Next() bool {")
+	f.Indent()
+	f.Line("for !s.done {")
+	f.Indent()
+	f.Line("data, err := s.read()")
+	f.Line("if err == nil {")
+	f.Indent()
+	f.Line("var event T")
+	f.Line("if err := json.Unmarshal(data, &event); err != nil {")
+	f.Indent()
+	f.Line("s.fail(err)")
+	f.Line("return false")
+	f.Dedent()
+	f.Line("}")
+	f.Line("s.event = event")
+	f.Line("return true")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if !s.sse {")
+	f.Indent()
+	f.Line("// NDJSON streams end with their connection.")
+	f.Line("if err == io.EOF {")
+	f.Indent()
+	f.Line("err = nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("s.fail(err)")
+	f.Line("return false")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if err := s.reconnect(); err != nil {")
+	f.Indent()
+	f.Line("s.fail(err)")
+	f.Line("return false")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return false")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Event returns the event read by the last call to Next.")
+	f.Line("func (s *Stream[T]) Event() T {")
+	f.Indent()
+	f.Line("return s.event")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Err returns the error that stopped the stream, or nil if it ended")
+	f.Line("// normally or was closed.")
+	f.Line("func (s *Stream[T]) Err() error {")
+	f.Indent()
+	f.Line("return s.err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// Close stops the stream and closes its connection.")
+	f.Line("func (s *Stream[T]) Close() error {")
+	f.Indent()
+	f.Line("s.done = true")
+	f.Line("return s.body.Close()")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("func (s *Stream[T]) fail(err error) {")
+	f.Indent()
+	f.Line("s.err = err")
+	f.Line("s.Close()")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// reconnect waits for the retry delay the server asked for and opens a")
+	f.Line("// new connection, resuming after the last event received.")
+	f.Line("func (s *Stream[T]) reconnect() error {")
+	f.Indent()
+	f.Line("s.body.Close()")
+	f.Line("select {")
+	f.Line("case <-s.ctx.Done():")
+	f.Indent()
+	f.Line("return s.ctx.Err()")
+	f.Dedent()
+	f.Line("case <-time.After(s.retry):")
+	f.Line("}")
+	f.Line("response, err := s.connect(s.lastID)")
+	checkErr("err")
+	f.Line("s.body, s.reader = response.Body, bufio.NewReader(response.Body)")
+	f.Line("return nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// read returns the data of the next event of the stream.")
+	f.Line("func (s *Stream[T]) read() ([]byte, error) {")
+	f.Indent()
+	f.Line("if !s.sse {")
+	f.Indent()
+	f.Line("for {")
+	f.Indent()
+	f.Line("line, err := s.reader.ReadString('\\n')")
+	f.Line(`if line = strings.TrimSpace(line); line != "" {`)
+	f.Indent()
+	f.Line("return []byte(line), nil")
+	f.Dedent()
+	f.Line("}")
+	checkErr("nil, err")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("var data []string")
+	f.Line("for {")
+	f.Indent()
+	f.Line("line, err := s.reader.ReadString('\\n')")
+	checkErr("nil, err")
+	f.Line(`line = strings.TrimRight(line, "\r\n")`)
+	f.Line(`if line == "" {`)
+	f.Indent()
+	f.Line("if len(data) > 0 {")
+	f.Indent()
+	f.Line(`return []byte(strings.Join(data, "\n")), nil`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("continue")
+	f.Dedent()
+	f.Line("}")
+	f.Line(`field, value, _ := strings.Cut(line, ":")`)
+	f.Line(`value = strings.TrimPrefix(value, " ")`)
+	f.Line("switch field {")
+	f.Line(`case "data":`)
+	f.Indent()
+	f.Line("data = append(data, value)")
+	f.Dedent()
+	f.Line(`case "id":`)
+	f.Indent()
+	f.Line("s.lastID = value")
+	f.Dedent()
+	f.Line(`case "retry":`)
+	f.Indent()
+	f.Line("if ms, err := strconv.Atoi(value); err == nil {")
+	f.Indent()
+	f.Line("s.retry = time.Duration(ms) * time.Millisecond")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+
+	f.Line("// EventSink sends the events of a streamed response to the client.")
+	f.Line("type EventSink[T any] interface {")
+	f.Indent()
+	f.Line("Send(event T) error")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// SSESink sends events to the client as server-sent events.")
+	f.Line("type SSESink[T any] struct {")
+	f.Indent()
+	f.Line("w  http.ResponseWriter")
+	f.Line("rc *http.ResponseController")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("func NewSSESink[T any](w http.ResponseWriter) *SSESink[T] {")
+	f.Indent()
+	f.Line(`w.Header().Set("Content-Type", %q)`, spec.SSE.ContentType())
+	f.Line(`w.Header().Set("Cache-Control", "no-cache")`)
+	f.Line("return &SSESink[T]{w: w, rc: http.NewResponseController(w)}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("func (s *SSESink[T]) Send(event T) error {")
+	f.Indent()
+	f.Line(`return s.SendWithID("", event)`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// SendWithID sends event with an ID, which clients that reconnect send")
+	f.Line("// back in their Last-Event-ID header to resume after it.")
+	f.Line("func (s *SSESink[T]) SendWithID(id string, event T) error {")
+	f.Indent()
+	f.Line("data, err := json.Marshal(event)")
+	checkErr("err")
+	f.Line(`if id != "" {`)
+	f.Indent()
+	f.Line(`if _, err := fmt.Fprintf(s.w, "id: %%s\n", id); err != nil {`)
+	f.Indent()
+	f.Line("return err")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line(`if _, err := fmt.Fprintf(s.w, "data: %%s\n\n", data); err != nil {`)
+	f.Indent()
+	f.Line("return err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return s.rc.Flush()")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("// NDJSONSink sends events to the client as newline-delimited JSON.")
+	f.Line("type NDJSONSink[T any] struct {")
+	f.Indent()
+	f.Line("w  http.ResponseWriter")
+	f.Line("rc *http.ResponseController")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("func NewNDJSONSink[T any](w http.ResponseWriter) *NDJSONSink[T] {")
+	f.Indent()
+	f.Line(`w.Header().Set("Content-Type", %q)`, spec.NDJSON.ContentType())
+	f.Line("return &NDJSONSink[T]{w: w, rc: http.NewResponseController(w)}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("func (s *NDJSONSink[T]) Send(event T) error {")
+	f.Indent()
+	f.Line("data, err := json.Marshal(event)")
+	checkErr("err")
+	f.Line(`if _, err := fmt.Fprintf(s.w, "%%s\n", data); err != nil {`)
+	f.Indent()
+	f.Line("return err")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return s.rc.Flush()")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	return f.String()
+}
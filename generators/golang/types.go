@@ -2,15 +2,34 @@ package golang
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"unicode"
 
 	"github.com/printchard/scapi/spec"
 )
 
+// Target is the side of an API that generated code is for.
+type Target string
+
+const (
+	ClientTarget Target = "client"
+	ServerTarget Target = "server"
+)
+
+// ReservedNames holds the helper types that generated code may declare, so
+// the API's types cannot be named after them.
+var ReservedNames = []string{"Client", "HTTPError", "Date", "Int64", "Violation", "ValidationError", "File", "Binary", "Stream", "EventSink", "SSESink", "NDJSONSink", "Channel"}
+
 type GoGenerator struct {
 	API      *spec.APISpec
 	Resolver spec.TypeResolver
 	Name     string
+	// Naming maps the qualified names of packaged types to Go identifiers.
+	Naming spec.Naming
+	// Target decides which packages the generated code imports, as the
+	// client and server use different ones. It defaults to the client.
+	Target Target
 }
 
 func capitalize(str string) string {
@@ -23,12 +42,58 @@ func capitalize(str string) string {
 	return str
 }
 
+// generateDocComment emits doc, if any, as a Go comment. A deprecation is
+// noted in a final paragraph of the form recognized by Go tooling.
+func generateDocComment(f *spec.Formatter, doc string, deprecated *spec.Deprecation) {
+	if deprecated != nil {
+		if doc != "" {
+			doc += "\n\n"
+		}
+		doc += "Deprecated: " + deprecated.String()
+	}
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		if line == "" {
+			f.Line("//")
+		} else {
+			f.Line("// %s", line)
+		}
+	}
+}
+
+// goFieldName returns the exported Go name of the field called name, which
+// may be a header name such as X-Request-Id.
+func goFieldName(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	result := ""
+	for _, part := range parts {
+		result += capitalize(part)
+	}
+	return result
+}
+
+func enumConstName(typeName, member string) string {
+	name := typeName
+	for _, part := range strings.Split(member, "_") {
+		if strings.ToUpper(part) == part {
+			part = strings.ToLower(part)
+		}
+		name += capitalize(part)
+	}
+	return name
+}
+
 func (g *GoGenerator) generateErrorTypeDef() string {
 	formatter := spec.NewFormatter()
 	formatter.Line("type HTTPError struct {")
 	formatter.Indent()
 	formatter.Line("Code int `json:\"code\"`")
 	formatter.Line("Body any `json:\"body\"`")
+	formatter.Line("Header http.Header `json:\"-\"`")
 	formatter.Dedent()
 	formatter.Line("}")
 	formatter.Line("")
@@ -41,21 +106,47 @@ func (g *GoGenerator) generateErrorTypeDef() string {
 	return formatter.String()
 }
 
+// inheritedFieldCounts counts how many of obj's parents provide each field.
+func (g *GoGenerator) inheritedFieldCounts(obj *spec.ObjectType) map[string]int {
+	counts := make(map[string]int)
+	for _, parent := range obj.Parents {
+		parentObj, _ := g.Resolver.ObjectOf(parent)
+		for fieldName := range parentObj.Fields {
+			counts[fieldName]++
+		}
+	}
+	return counts
+}
+
 func (g *GoGenerator) generateObjectTypeDef(typeName string, obj *spec.ObjectType) string {
 	formatter := spec.NewFormatter()
-	formatter.Line("type %s struct {", typeName)
+	typeParams := ""
+	if len(obj.TypeParams) > 0 {
+		typeParams = "[" + strings.Join(obj.TypeParams, ", ") + " any]"
+	}
+	if obj.Derivation != nil {
+		formatter.Line("// %s is derived from %s.", typeName, obj.Derivation)
+	}
+	formatter.Line("type %s%s struct {", typeName, typeParams)
 	formatter.Indent()
+	for _, parent := range obj.Parents {
+		formatter.Line("%s", g.generateGoType(parent, false))
+	}
+	inherited := g.inheritedFieldCounts(obj)
 	for fieldName, field := range obj.Fields {
-		tags := fmt.Sprintf("`json:\"%s", fieldName)
-		goType := g.generateGoType(field.Ref, field.Optional)
-		if field.Cardinality == spec.Multiple {
-			goType = "[]" + goType
+		// Fields provided by exactly one embedded parent are promoted by
+		// encoding/json; ambiguous ones must be redeclared to be encoded.
+		if inherited[fieldName] == 1 {
+			continue
 		}
+		tags := fmt.Sprintf("`json:\"%s", fieldName)
+		goType := g.generateFieldGoType(field)
 		if field.Optional {
 			tags += ",omitempty"
 		}
 		tags += "\"`"
 
+		generateDocComment(formatter, field.Doc, field.Deprecated)
 		formatter.Line("%s %s %s", capitalize(fieldName), goType, tags)
 	}
 	formatter.Dedent()
@@ -64,7 +155,157 @@ func (g *GoGenerator) generateObjectTypeDef(typeName string, obj *spec.ObjectTyp
 	return formatter.String()
 }
 
+func (g *GoGenerator) generateEnumTypeDef(typeName string, enum *spec.EnumType) string {
+	formatter := spec.NewFormatter()
+	formatter.Line("type %s string", typeName)
+	formatter.Line("")
+	formatter.Line("const (")
+	formatter.Indent()
+	for _, member := range enum.Members {
+		generateDocComment(formatter, "", enum.Deprecated[member])
+		formatter.Line("%s %s = \"%s\"", enumConstName(typeName, member), typeName, member)
+	}
+	formatter.Dedent()
+	formatter.Line(")")
+	formatter.Line("")
+	formatter.Line("func (e %s) IsValid() bool {", typeName)
+	formatter.Indent()
+	formatter.Line("switch e {")
+	consts := make([]string, len(enum.Members))
+	for i, member := range enum.Members {
+		consts[i] = enumConstName(typeName, member)
+	}
+	formatter.Line("case %s:", strings.Join(consts, ", "))
+	formatter.Indent()
+	formatter.Line("return true")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("return false")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func (g *GoGenerator) generateUnionTypeDef(typeName string, union *spec.UnionType) string {
+	variantIface := typeName + "Variant"
+	marker := "is" + typeName
+	tagField := capitalize(union.Discriminator)
+
+	formatter := spec.NewFormatter()
+	formatter.Line("type %s interface {", variantIface)
+	formatter.Indent()
+	formatter.Line("%s()", marker)
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	for _, variant := range union.Variants {
+		formatter.Line("func (%s) %s() {}", g.Naming.TypeName(variant.Name), marker)
+	}
+	formatter.Line("")
+	formatter.Line("type %s struct {", typeName)
+	formatter.Indent()
+	formatter.Line("Value %s", variantIface)
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+
+	formatter.Line("func (u %s) MarshalJSON() ([]byte, error) {", typeName)
+	formatter.Indent()
+	formatter.Line("switch v := u.Value.(type) {")
+	for _, variant := range union.Variants {
+		formatter.Line("case %s:", g.Naming.TypeName(variant.Name))
+		formatter.Indent()
+		formatter.Line("v.%s = \"%s\"", tagField, spec.VariantTag(variant))
+		formatter.Line("return json.Marshal(v)")
+		formatter.Dedent()
+	}
+	formatter.Line("}")
+	formatter.Line("return nil, fmt.Errorf(\"%s: unknown variant %%T\", u.Value)", typeName)
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+
+	formatter.Line("func (u *%s) UnmarshalJSON(data []byte) error {", typeName)
+	formatter.Indent()
+	formatter.Line("var tag struct {")
+	formatter.Indent()
+	formatter.Line("%s string `json:\"%s\"`", tagField, union.Discriminator)
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("if err := json.Unmarshal(data, &tag); err != nil {")
+	formatter.Indent()
+	formatter.Line("return err")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("switch tag.%s {", tagField)
+	for _, variant := range union.Variants {
+		formatter.Line("case \"%s\":", spec.VariantTag(variant))
+		formatter.Indent()
+		formatter.Line("var v %s", g.Naming.TypeName(variant.Name))
+		formatter.Line("if err := json.Unmarshal(data, &v); err != nil {")
+		formatter.Indent()
+		formatter.Line("return err")
+		formatter.Dedent()
+		formatter.Line("}")
+		formatter.Line("u.Value = v")
+		formatter.Dedent()
+	}
+	formatter.Line("default:")
+	formatter.Indent()
+	formatter.Line("return fmt.Errorf(\"%s: unknown %s %%q\", tag.%s)", typeName, union.Discriminator, tagField)
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("return nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func (g *GoGenerator) generateFieldGoType(field spec.Field) string {
+	switch field.Cardinality {
+	case spec.Multiple:
+		// A nil slice already stands for an absent optional array.
+		return "[]" + g.generateGoType(field.Ref, false)
+	case spec.Map:
+		return fmt.Sprintf("map[%s]%s", g.generateGoType(*field.Key, false), g.generateGoType(field.Ref, false))
+	default:
+		return g.generateGoType(field.Ref, field.Optional)
+	}
+}
+
+// isPointerField reports whether field is held by a pointer, as optional
+// fields are unless their Go type, like that of bytes, is already nillable.
+func (g *GoGenerator) isPointerField(field spec.Field) bool {
+	return strings.HasPrefix(g.generateFieldGoType(field), "*")
+}
+
+// fieldValue returns the expression reading the value of the optional field
+// held in expr, dereferencing it if it is a pointer.
+func (g *GoGenerator) fieldValue(field spec.Field, expr string) string {
+	if g.isPointerField(field) {
+		return "(*" + expr + ")"
+	}
+	return expr
+}
+
 func (g *GoGenerator) generateGoType(tRef spec.TypeRef, optional bool) string {
+	if tRef.IsArray() {
+		return "[]" + g.generateGoType(*tRef.Elem, optional)
+	}
+	if tRef.TypeParam {
+		if optional {
+			return "*" + tRef.Name
+		}
+		return tRef.Name
+	}
+	if g.Resolver.IsAlias(tRef) {
+		if optional {
+			return "*" + g.Naming.TypeName(tRef.Name)
+		}
+		return g.Naming.TypeName(tRef.Name)
+	}
 	if g.Resolver.IsPrimitive(tRef) {
 		primType, _ := g.Resolver.PrimitiveOf(tRef)
 		var typ string
@@ -77,6 +318,19 @@ func (g *GoGenerator) generateGoType(tRef spec.TypeRef, optional bool) string {
 			typ = "float64"
 		case spec.Boolean:
 			typ = "bool"
+		case spec.DateTime:
+			typ = "time.Time"
+		case spec.Date:
+			typ = "Date"
+		case spec.UUID, spec.Email, spec.URL, spec.Decimal:
+			typ = "string"
+		case spec.Bytes:
+			// encoding/json encodes []byte as a base64 string.
+			return "[]byte"
+		case spec.Int64:
+			typ = "Int64"
+		case spec.File:
+			typ = "File"
 		default:
 			return "any"
 		}
@@ -84,42 +338,474 @@ func (g *GoGenerator) generateGoType(tRef spec.TypeRef, optional bool) string {
 			return "*" + typ
 		}
 		return typ
-	} else if g.Resolver.IsObject(tRef) {
+	} else if g.Resolver.IsObject(tRef) || g.Resolver.IsEnum(tRef) || g.Resolver.IsUnion(tRef) {
+		name := g.Naming.TypeName(tRef.Name)
+		if len(tRef.Args) > 0 {
+			args := make([]string, len(tRef.Args))
+			for i, arg := range tRef.Args {
+				args[i] = g.generateGoType(arg, false)
+			}
+			name += "[" + strings.Join(args, ", ") + "]"
+		}
 		if optional {
-			return "*" + tRef.Name
+			return "*" + name
 		}
-		return tRef.Name
+		return name
 	}
 	return "any"
 }
 
+// usedPrimitives reports which primitive types the API refers to, directly or
+// through aliases.
+func (g *GoGenerator) usedPrimitives() map[spec.PrimitiveType]bool {
+	used := make(map[spec.PrimitiveType]bool)
+	var visit func(ref spec.TypeRef)
+	visit = func(ref spec.TypeRef) {
+		if ref.IsArray() {
+			visit(*ref.Elem)
+			return
+		}
+		if ref.TypeParam {
+			return
+		}
+		for _, arg := range ref.Args {
+			visit(arg)
+		}
+		if primType, ok := g.Resolver.PrimitiveOf(ref); ok {
+			used[primType] = true
+		}
+	}
+	visitField := func(field spec.Field) {
+		visit(field.Ref)
+		if field.Key != nil {
+			visit(*field.Key)
+		}
+	}
+	for _, typ := range g.API.Types {
+		switch typ.Kind {
+		case spec.Object:
+			for _, field := range typ.ObjectType.Fields {
+				visitField(field)
+			}
+		case spec.Alias:
+			visit(typ.AliasType.Target)
+		}
+	}
+	for _, endpoint := range g.API.Endpoints {
+		if endpoint.Input != nil {
+			for _, field := range endpoint.Input.Params {
+				visitField(field)
+			}
+			for _, field := range endpoint.Input.Query {
+				visitField(field)
+			}
+			for _, field := range endpoint.Input.Headers {
+				visitField(field)
+			}
+			for _, field := range endpoint.Input.Cookies {
+				visitField(field)
+			}
+			if endpoint.Input.Body != nil {
+				visit(*endpoint.Input.Body)
+			}
+		}
+		for _, resp := range endpoint.Responses {
+			if resp.Ref != nil {
+				visit(*resp.Ref)
+			}
+			for _, field := range resp.Headers {
+				visitField(field)
+			}
+		}
+	}
+	for _, channel := range g.API.Channels {
+		visit(channel.Client)
+		visit(channel.Server)
+	}
+	return used
+}
+
+func (g *GoGenerator) generateImports(used map[spec.PrimitiveType]bool, constraints map[spec.ConstraintKind]bool) string {
+	var imports []string
+	if len(g.API.Endpoints) > 0 || len(g.API.Channels) > 0 {
+		imports = append(imports, "context")
+	}
+	imports = append(imports, "fmt", "net/http")
+	if g.usesJSON(used) {
+		imports = append(imports, "encoding/json")
+	}
+	if g.Target != ServerTarget && len(g.API.Endpoints) > 0 {
+		// Clients build the query of every request.
+		imports = append(imports, "net/url")
+	}
+	if used[spec.DateTime] || used[spec.Date] {
+		imports = append(imports, "time")
+	}
+	if used[spec.Int64] {
+		imports = append(imports, "strconv")
+	}
+	if g.usesBase64() {
+		imports = append(imports, "encoding/base64")
+	}
+	imports = append(imports, g.bodyImports()...)
+	if len(g.API.Channels) > 0 {
+		imports = append(imports, "bufio", "crypto/rand", "crypto/sha1", "encoding/base64", "encoding/binary", "io", "strings", "sync")
+	}
+	if constraints[spec.Pattern] {
+		imports = append(imports, "regexp")
+	}
+	if constraints[spec.MinLength] || constraints[spec.MaxLength] {
+		imports = append(imports, "unicode/utf8")
+	}
+	formatter := spec.NewFormatter()
+	formatter.Line("package main")
+	formatter.Line("")
+	formatter.Line("import (")
+	formatter.Indent()
+	seen := make(map[string]bool)
+	for _, imp := range imports {
+		if !seen[imp] {
+			formatter.Line("%q", imp)
+			seen[imp] = true
+		}
+	}
+	formatter.Dedent()
+	formatter.Line(")")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// generateDateTypeDef emits the Date type used for date primitives, which
+// encodes as an RFC 3339 full-date.
+func (g *GoGenerator) generateDateTypeDef() string {
+	formatter := spec.NewFormatter()
+	formatter.Line("type Date struct {")
+	formatter.Indent()
+	formatter.Line("time.Time")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("func (d Date) String() string {")
+	formatter.Indent()
+	formatter.Line("return d.Format(time.DateOnly)")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("func (d Date) MarshalJSON() ([]byte, error) {")
+	formatter.Indent()
+	formatter.Line("return json.Marshal(d.String())")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("func (d *Date) UnmarshalJSON(data []byte) error {")
+	formatter.Indent()
+	formatter.Line("var s string")
+	formatter.Line("if err := json.Unmarshal(data, &s); err != nil {")
+	formatter.Indent()
+	formatter.Line("return err")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("t, err := time.Parse(time.DateOnly, s)")
+	formatter.Line("if err != nil {")
+	formatter.Indent()
+	formatter.Line("return err")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("d.Time = t")
+	formatter.Line("return nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// generateInt64TypeDef emits the Int64 type used for int64 primitives, which
+// encodes as a JSON string of its decimal digits, since JavaScript numbers
+// cannot represent every int64.
+func (g *GoGenerator) generateInt64TypeDef() string {
+	formatter := spec.NewFormatter()
+	formatter.Line("type Int64 int64")
+	formatter.Line("")
+	formatter.Line("func (n Int64) MarshalJSON() ([]byte, error) {")
+	formatter.Indent()
+	formatter.Line("return json.Marshal(strconv.FormatInt(int64(n), 10))")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("func (n *Int64) UnmarshalJSON(data []byte) error {")
+	formatter.Indent()
+	formatter.Line("var s string")
+	formatter.Line("if err := json.Unmarshal(data, &s); err != nil {")
+	formatter.Indent()
+	formatter.Line("return err")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("v, err := strconv.ParseInt(s, 10, 64)")
+	formatter.Line("if err != nil {")
+	formatter.Indent()
+	formatter.Line("return err")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("*n = Int64(v)")
+	formatter.Line("return nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// usesJSON reports whether the generated code calls encoding/json itself,
+// rather than only tagging fields for it. used holds the primitives the API
+// refers to.
+func (g *GoGenerator) usesJSON(used map[spec.PrimitiveType]bool) bool {
+	if used[spec.Date] || used[spec.Int64] || g.hasStreamResponse() || len(g.API.Channels) > 0 {
+		return true
+	}
+	for _, typ := range g.API.Types {
+		if typ.Kind == spec.Union {
+			return true
+		}
+	}
+	for _, endpoint := range g.API.Endpoints {
+		if endpoint.Input != nil && endpoint.Input.Body != nil && endpoint.Input.Encoding() == spec.JSONEncoding {
+			return true
+		}
+		if g.Target == ServerTarget {
+			continue
+		}
+		if decodesResponses(endpoint) {
+			return true
+		}
+	}
+	return false
+}
+
+// usesBase64 reports whether the generated code encodes or decodes bytes
+// outside of JSON, which encodes them itself: in the values of paths,
+// queries, headers, cookies and forms. Channel handshakes use base64 too.
+func (g *GoGenerator) usesBase64() bool {
+	if len(g.API.Channels) > 0 {
+		return true
+	}
+	server := g.Target == ServerTarget
+	isBytes := func(fields map[string]spec.Field) bool {
+		for _, field := range fields {
+			if primType, _ := g.Resolver.PrimitiveOf(field.Ref); primType == spec.Bytes {
+				return true
+			}
+		}
+		return false
+	}
+	for _, endpoint := range g.API.Endpoints {
+		if input := endpoint.Input; input != nil {
+			if isBytes(input.Params) || isBytes(input.Query) || isBytes(input.Headers) || isBytes(input.Cookies) {
+				return true
+			}
+			if input.Body != nil && (input.Encoding() == spec.FormEncoding || input.IsMultipart()) {
+				if obj, _ := g.Resolver.ObjectOf(*input.Body); isBytes(obj.Fields) {
+					return true
+				}
+			}
+		}
+		// Only clients parse response headers.
+		if !server && isBytes(g.Resolver.ResolveSuccessResponse(endpoint).Headers) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyImports returns the packages used to encode and decode the request
+// and response bodies of all endpoints. Clients encode request bodies and
+// servers decode them, so the packages depend on the target.
+func (g *GoGenerator) bodyImports() []string {
+	server := g.Target == ServerTarget
+	used := make(map[string]bool)
+	if g.hasBinaryResponse() {
+		used["io"] = true
+	}
+	if g.hasStreamResponse() {
+		for _, imp := range []string{"bufio", "io", "strconv", "strings", "time"} {
+			used[imp] = true
+		}
+	}
+	for _, endpoint := range g.API.Endpoints {
+		if endpoint.Input == nil || endpoint.Input.Body == nil {
+			continue
+		}
+		switch endpoint.Input.Encoding() {
+		case spec.FormEncoding:
+			if !server {
+				used["strings"] = true
+			}
+		case spec.MultipartEncoding:
+			used["io"], used["mime/multipart"] = true, true
+		case spec.RawEncoding:
+			if server {
+				used["io"] = true
+			} else if primType, _ := g.Resolver.PrimitiveOf(*endpoint.Input.Body); primType == spec.Bytes {
+				used["bytes"] = true
+			} else {
+				used["strings"] = true
+			}
+		default:
+			if !server {
+				used["bytes"] = true
+			}
+		}
+	}
+	imports := make([]string, 0, len(used))
+	for imp := range used {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	return imports
+}
+
+// hasBinaryResponse reports whether any endpoint responds with a binary
+// body.
+func (g *GoGenerator) hasBinaryResponse() bool {
+	for _, endpoint := range g.API.Endpoints {
+		if g.Resolver.ResolveSuccessResponse(endpoint).IsBinary() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMultipart reports whether any endpoint takes a multipart body.
+func (g *GoGenerator) hasMultipart() bool {
+	for _, endpoint := range g.API.Endpoints {
+		if endpoint.Input != nil && endpoint.Input.IsMultipart() {
+			return true
+		}
+	}
+	return false
+}
+
+// generateFileTypeDef emits the File type used for file primitives.
+func (g *GoGenerator) generateFileTypeDef() string {
+	formatter := spec.NewFormatter()
+	formatter.Line("// File is a file sent as a part of a multipart body.")
+	formatter.Line("type File struct {")
+	formatter.Indent()
+	formatter.Line("Name    string")
+	formatter.Line("Content io.Reader")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func (g *GoGenerator) generateAliasTypeDef(typeName string, alias *spec.AliasType) string {
+	// Defined types do not inherit methods, so aliases of primitives with
+	// their own JSON encoding must be type aliases to keep it.
+	if primType, _ := g.Resolver.PrimitiveOf(alias.Target); primType == spec.DateTime || primType == spec.Date || primType == spec.Int64 {
+		return fmt.Sprintf("type %s = %s\n\n", typeName, g.generateGoType(alias.Target, false))
+	}
+	return fmt.Sprintf("type %s %s\n\n", typeName, g.generateGoType(alias.Target, false))
+}
+
 func (g *GoGenerator) GenerateTypeDefs() string {
-	result := "package main\n\nimport (\n  \"context\"\n  \"fmt\"\n  \"net/url\"\n  \"encoding/json\"\n  \"net/http\"\n)\n\n"
+	used := g.usedPrimitives()
+	result := g.generateImports(used, g.usedConstraints())
 	result += g.generateErrorTypeDef()
-	for typeName, typ := range g.API.Types {
-		if typ.Kind != spec.Object {
-			continue
+	result += g.generateValidationTypeDefs()
+	if used[spec.Date] {
+		result += g.generateDateTypeDef()
+	}
+	if used[spec.Int64] {
+		result += g.generateInt64TypeDef()
+	}
+	if g.hasMultipart() {
+		result += g.generateFileTypeDef()
+	}
+	if g.hasStreamResponse() {
+		result += g.generateStreamTypeDefs()
+	}
+	if len(g.API.Channels) > 0 {
+		result += g.generateChannelTypeDefs()
+	}
+	for name, typ := range g.API.Types {
+		typeName := g.Naming.TypeName(name)
+		doc := spec.NewFormatter()
+		generateDocComment(doc, typ.Doc, typ.Deprecated)
+		result += doc.String()
+		switch typ.Kind {
+		case spec.Object:
+			result += g.generateObjectTypeDef(typeName, typ.ObjectType)
+			receiverType := typeName
+			if len(typ.ObjectType.TypeParams) > 0 {
+				receiverType += "[" + strings.Join(typ.ObjectType.TypeParams, ", ") + "]"
+			}
+			result += g.generateValidateMethods(typeName, receiverType, typ.ObjectType.Fields)
+			result += g.generateApplyDefaults(receiverType, typ.ObjectType.Fields)
+		case spec.Enum:
+			result += g.generateEnumTypeDef(typeName, typ.EnumType)
+		case spec.Union:
+			result += g.generateUnionTypeDef(typeName, typ.UnionType)
+			result += g.generateUnionValidateMethods(typeName)
+		case spec.Alias:
+			result += g.generateAliasTypeDef(typeName, typ.AliasType)
 		}
-		result += g.generateObjectTypeDef(typeName, typ.ObjectType)
 	}
 	return result
 }
 
+// goValue returns the Go expression for value as a value of type ref.
+func (g *GoGenerator) goValue(ref spec.TypeRef, value *spec.Value) string {
+	switch {
+	case g.Resolver.IsEnum(ref):
+		return enumConstName(g.Naming.TypeName(ref.Name), value.Literal)
+	case value.Kind == spec.StringValue:
+		return fmt.Sprintf("%q", value.Literal)
+	default:
+		return value.Literal
+	}
+}
+
+// generateApplyDefaults emits an ApplyDefaults method that sets absent
+// optional fields of receiverType to their default values. Nothing is
+// emitted if no field has a default.
+func (g *GoGenerator) generateApplyDefaults(receiverType string, fields map[string]spec.Field) string {
+	formatter := spec.NewFormatter()
+	formatter.Line("func (o *%s) ApplyDefaults() {", receiverType)
+	formatter.Indent()
+	hasDefaults := false
+	for fieldName, field := range fields {
+		if field.Default == nil {
+			continue
+		}
+		hasDefaults = true
+		name := goFieldName(fieldName)
+		formatter.Line("if o.%s == nil {", name)
+		formatter.Indent()
+		formatter.Line("var v %s = %s", g.generateGoType(field.Ref, false), g.goValue(field.Ref, field.Default))
+		formatter.Line("o.%s = &v", name)
+		formatter.Dedent()
+		formatter.Line("}")
+	}
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	if !hasDefaults {
+		return ""
+	}
+	return formatter.String()
+}
+
 func (g *GoGenerator) generateParamsWrapper(endpoint spec.Endpoint) string {
 	formatter := spec.NewFormatter()
 	formatter.Line("type %sParams struct {", endpoint.Name)
 	formatter.Indent()
 	for paramName, field := range endpoint.Input.Params {
-		goType := g.generateGoType(field.Ref, field.Optional)
-		if field.Cardinality == spec.Multiple {
-			goType = "[]" + goType
-		}
-		formatter.Line("%s %s", capitalize(paramName), goType)
+		generateDocComment(formatter, field.Doc, field.Deprecated)
+		formatter.Line("%s %s", capitalize(paramName), g.generateFieldGoType(field))
 	}
 	formatter.Dedent()
 	formatter.Line("}")
 	formatter.Line("")
-	return formatter.String()
+	return formatter.String() + g.generateValidateMethods(endpoint.Name+"Params", endpoint.Name+"Params", endpoint.Input.Params)
 }
 
 func (g *GoGenerator) generateQueryWrapper(endpoint spec.Endpoint) string {
@@ -128,11 +814,105 @@ func (g *GoGenerator) generateQueryWrapper(endpoint spec.Endpoint) string {
 	formatter.Indent()
 	for queryName, field := range endpoint.Input.Query {
 		goType := g.generateGoType(field.Ref, field.Optional)
+		if field.Cardinality == spec.Multiple {
+			goType = "[]" + g.generateGoType(field.Ref, false)
+		}
+		generateDocComment(formatter, field.Doc, field.Deprecated)
 		formatter.Line("%s %s", capitalize(queryName), goType)
 	}
 	formatter.Dedent()
 	formatter.Line("}")
 	formatter.Line("")
+	result := formatter.String() + g.generateValidateMethods(endpoint.Name+"Query", endpoint.Name+"Query", endpoint.Input.Query)
+	return result + g.generateApplyDefaults(endpoint.Name+"Query", endpoint.Input.Query)
+}
+
+// generateHeadersWrapper emits the request headers of endpoint as a struct
+// with a field for each header.
+func (g *GoGenerator) generateHeadersWrapper(endpoint spec.Endpoint) string {
+	formatter := spec.NewFormatter()
+	formatter.Line("type %sHeaders struct {", endpoint.Name)
+	formatter.Indent()
+	for headerName, field := range endpoint.Input.Headers {
+		generateDocComment(formatter, field.Doc, field.Deprecated)
+		formatter.Line("%s %s", goFieldName(headerName), g.generateFieldGoType(field))
+	}
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	result := formatter.String() + g.generateValidateMethods(endpoint.Name+"Headers", endpoint.Name+"Headers", endpoint.Input.Headers)
+	return result + g.generateApplyDefaults(endpoint.Name+"Headers", endpoint.Input.Headers)
+}
+
+// generateCookiesWrapper emits the request cookies of endpoint as a struct
+// with a field for each cookie.
+func (g *GoGenerator) generateCookiesWrapper(endpoint spec.Endpoint) string {
+	formatter := spec.NewFormatter()
+	formatter.Line("type %sCookies struct {", endpoint.Name)
+	formatter.Indent()
+	for cookieName, field := range endpoint.Input.Cookies {
+		generateDocComment(formatter, field.Doc, field.Deprecated)
+		formatter.Line("%s %s", goFieldName(cookieName), g.generateFieldGoType(field))
+	}
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	result := formatter.String() + g.generateValidateMethods(endpoint.Name+"Cookies", endpoint.Name+"Cookies", endpoint.Input.Cookies)
+	return result + g.generateApplyDefaults(endpoint.Name+"Cookies", endpoint.Input.Cookies)
+}
+
+// resultType returns the Go type an endpoint's handler returns a pointer
+// to: the success response body, or a wrapper that also holds the response
+// headers if the success response declares any.
+func (g *GoGenerator) resultType(endpoint spec.Endpoint) string {
+	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
+	if len(successResp.Headers) > 0 {
+		return endpoint.Name + "Response"
+	}
+	return g.responseBodyType(*successResp)
+}
+
+// responseBodyType returns the Go type of the body of resp. Responses without
+// a body have an empty struct, so that every handler returns a result.
+func (g *GoGenerator) responseBodyType(resp spec.Response) string {
+	if resp.IsBinary() {
+		return "Binary"
+	}
+	if resp.IsEmpty() {
+		return "struct{}"
+	}
+	if resp.IsStream() {
+		return "Stream[" + g.generateGoType(*resp.Ref, false) + "]"
+	}
+	return g.generateGoType(*resp.Ref, false)
+}
+
+// generateResponseWrapper emits the result type of an endpoint whose success
+// response declares headers. Nothing is emitted otherwise.
+func (g *GoGenerator) generateResponseWrapper(endpoint spec.Endpoint) string {
+	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
+	if len(successResp.Headers) == 0 {
+		return ""
+	}
+	formatter := spec.NewFormatter()
+	formatter.Line("type %sResponseHeaders struct {", endpoint.Name)
+	formatter.Indent()
+	for headerName, field := range successResp.Headers {
+		generateDocComment(formatter, field.Doc, field.Deprecated)
+		formatter.Line("%s %s", goFieldName(headerName), g.generateFieldGoType(field))
+	}
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("type %sResponse struct {", endpoint.Name)
+	formatter.Indent()
+	if !successResp.IsEmpty() {
+		formatter.Line("Body %s", g.responseBodyType(*successResp))
+	}
+	formatter.Line("Headers %sResponseHeaders", endpoint.Name)
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
 	return formatter.String()
 }
 
@@ -151,9 +931,18 @@ func (g *GoGenerator) generateInputWrapper(endpoint spec.Endpoint) string {
 		subTypeDefs += g.generateQueryWrapper(endpoint)
 	}
 
-	if endpoint.Input.Body != nil {
-		formatter.Line("Body %s", endpoint.Input.Body.Name)
+	if len(endpoint.Input.Headers) > 0 {
+		formatter.Line("Headers %sHeaders", endpoint.Name)
+		subTypeDefs += g.generateHeadersWrapper(endpoint)
+	}
+
+	if len(endpoint.Input.Cookies) > 0 {
+		formatter.Line("Cookies %sCookies", endpoint.Name)
+		subTypeDefs += g.generateCookiesWrapper(endpoint)
+	}
 
+	if endpoint.Input.Body != nil {
+		formatter.Line("Body %s", g.generateGoType(*endpoint.Input.Body, false))
 	}
 
 	formatter.Dedent()
@@ -0,0 +1,245 @@
+package golang
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/printchard/scapi/spec"
+)
+
+// generateValidationTypeDefs emits the error returned by generated Validate
+// methods and the helpers those methods share.
+func (g *GoGenerator) generateValidationTypeDefs() string {
+	formatter := spec.NewFormatter()
+	formatter.Line("type Violation struct {")
+	formatter.Indent()
+	formatter.Line("Field string `json:\"field\"`")
+	formatter.Line("Message string `json:\"message\"`")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("type ValidationError struct {")
+	formatter.Indent()
+	formatter.Line("Violations []Violation `json:\"violations\"`")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("func (e *ValidationError) Error() string {")
+	formatter.Indent()
+	formatter.Line("msg := \"validation failed\"")
+	formatter.Line("for _, v := range e.Violations {")
+	formatter.Indent()
+	formatter.Line("msg += fmt.Sprintf(\"; %%s %%s\", v.Field, v.Message)")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("return msg")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("type validator interface {")
+	formatter.Indent()
+	formatter.Line("validate(path string) []Violation")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("func fieldPath(path, name string) string {")
+	formatter.Indent()
+	formatter.Line("if path == \"\" {")
+	formatter.Indent()
+	formatter.Line("return name")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("return path + \".\" + name")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+// usedConstraints reports which constraint kinds appear on the fields of
+// the API's types and endpoint inputs.
+func (g *GoGenerator) usedConstraints() map[spec.ConstraintKind]bool {
+	used := make(map[spec.ConstraintKind]bool)
+	visit := func(fields map[string]spec.Field) {
+		for _, field := range fields {
+			for _, constraint := range field.Constraints {
+				used[constraint.Kind] = true
+			}
+		}
+	}
+	for _, typ := range g.API.Types {
+		if typ.Kind == spec.Object {
+			visit(typ.ObjectType.Fields)
+		}
+	}
+	for _, endpoint := range g.API.Endpoints {
+		if endpoint.Input != nil {
+			visit(endpoint.Input.Params)
+			visit(endpoint.Input.Query)
+			visit(endpoint.Input.Headers)
+			visit(endpoint.Input.Cookies)
+		}
+	}
+	return used
+}
+
+func patternVarName(typeName, fieldName string) string {
+	return "pattern" + typeName + goFieldName(fieldName)
+}
+
+// generateValidateMethods emits Validate and validate methods for the struct
+// receiverType, whose fields are fields. Violations are reported with the
+// dotted path of the offending field.
+func (g *GoGenerator) generateValidateMethods(typeName, receiverType string, fields map[string]spec.Field) string {
+	formatter := spec.NewFormatter()
+	for fieldName, field := range fields {
+		for _, constraint := range field.Constraints {
+			if constraint.Kind == spec.Pattern {
+				formatter.Line("var %s = regexp.MustCompile(%q)", patternVarName(typeName, fieldName), constraint.Value)
+				formatter.Line("")
+			}
+		}
+	}
+	g.generateValidateEntryPoint(formatter, receiverType, "o")
+	formatter.Line("func (o %s) validate(path string) []Violation {", receiverType)
+	formatter.Indent()
+	formatter.Line("var violations []Violation")
+	for fieldName, field := range fields {
+		g.generateFieldValidation(formatter, typeName, fieldName, field)
+	}
+	formatter.Line("return violations")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func (g *GoGenerator) generateValidateEntryPoint(f *spec.Formatter, receiverType, receiver string) {
+	f.Line("func (%s %s) Validate() error {", receiver, receiverType)
+	f.Indent()
+	f.Line("if violations := %s.validate(\"\"); len(violations) > 0 {", receiver)
+	f.Indent()
+	f.Line("return &ValidationError{Violations: violations}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return nil")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+}
+
+func (g *GoGenerator) generateUnionValidateMethods(typeName string) string {
+	formatter := spec.NewFormatter()
+	g.generateValidateEntryPoint(formatter, typeName, "u")
+	formatter.Line("func (u %s) validate(path string) []Violation {", typeName)
+	formatter.Indent()
+	formatter.Line("if v, ok := u.Value.(validator); ok {")
+	formatter.Indent()
+	formatter.Line("return v.validate(path)")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("return nil")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func constraintCheck(constraint spec.Constraint, expr, patternVar string) string {
+	switch constraint.Kind {
+	case spec.MinLength:
+		return fmt.Sprintf("utf8.RuneCountInString(string(%s)) < %s", expr, constraint.Value)
+	case spec.MaxLength:
+		return fmt.Sprintf("utf8.RuneCountInString(string(%s)) > %s", expr, constraint.Value)
+	case spec.Min:
+		return fmt.Sprintf("%s < %s", expr, constraint.Value)
+	case spec.Max:
+		return fmt.Sprintf("%s > %s", expr, constraint.Value)
+	case spec.Pattern:
+		return fmt.Sprintf("!%s.MatchString(string(%s))", patternVar, expr)
+	case spec.MinItems:
+		return fmt.Sprintf("len(%s) < %s", expr, constraint.Value)
+	case spec.MaxItems:
+		return fmt.Sprintf("len(%s) > %s", expr, constraint.Value)
+	default:
+		return "false"
+	}
+}
+
+func (g *GoGenerator) generateFieldValidation(f *spec.Formatter, typeName, fieldName string, field spec.Field) {
+	expr := "o." + goFieldName(fieldName)
+	path := fmt.Sprintf("fieldPath(path, %q)", fieldName)
+	if len(field.Constraints) == 0 && !g.needsValidation(field.Ref) {
+		return
+	}
+	if field.Optional {
+		f.Line("if %s != nil {", expr)
+		f.Indent()
+		expr = g.fieldValue(field, expr)
+	}
+	for _, constraint := range field.Constraints {
+		f.Line("if %s {", constraintCheck(constraint, expr, patternVarName(typeName, fieldName)))
+		f.Indent()
+		f.Line("violations = append(violations, Violation{Field: %s, Message: %q})", path, constraint.Message())
+		f.Dedent()
+		f.Line("}")
+	}
+	if g.needsValidation(field.Ref) {
+		switch field.Cardinality {
+		case spec.Multiple:
+			f.Line("for i, item := range %s {", expr)
+			f.Indent()
+			g.generateValueValidation(f, field.Ref, "item", fmt.Sprintf(`fmt.Sprintf("%%s[%%d]", %s, i)`, path), 1)
+			f.Dedent()
+			f.Line("}")
+		case spec.Map:
+			f.Line("for k, item := range %s {", expr)
+			f.Indent()
+			g.generateValueValidation(f, field.Ref, "item", fmt.Sprintf(`fmt.Sprintf("%%s[%%v]", %s, k)`, path), 1)
+			f.Dedent()
+			f.Line("}")
+		default:
+			g.generateValueValidation(f, field.Ref, expr, path, 0)
+		}
+	}
+	if field.Optional {
+		f.Dedent()
+		f.Line("}")
+	}
+}
+
+// needsValidation reports whether values of ref can hold violations of their
+// own: objects, unions and enums, or type parameters that may be bound to one.
+func (g *GoGenerator) needsValidation(ref spec.TypeRef) bool {
+	if ref.IsArray() {
+		return g.needsValidation(*ref.Elem)
+	}
+	return ref.TypeParam || g.Resolver.IsObject(ref) || g.Resolver.IsUnion(ref) || g.Resolver.IsEnum(ref)
+}
+
+func (g *GoGenerator) generateValueValidation(f *spec.Formatter, ref spec.TypeRef, expr, path string, depth int) {
+	switch {
+	case ref.IsArray():
+		index, item := fmt.Sprintf("i%d", depth), fmt.Sprintf("item%d", depth)
+		f.Line("for %s, %s := range %s {", index, item, expr)
+		f.Indent()
+		g.generateValueValidation(f, *ref.Elem, item, fmt.Sprintf(`fmt.Sprintf("%%s[%%d]", %s, %s)`, path, index), depth+1)
+		f.Dedent()
+		f.Line("}")
+	case ref.TypeParam:
+		f.Line("if v, ok := any(%s).(validator); ok {", expr)
+		f.Indent()
+		f.Line("violations = append(violations, v.validate(%s)...)", path)
+		f.Dedent()
+		f.Line("}")
+	case g.Resolver.IsEnum(ref):
+		enum, _ := g.Resolver.EnumOf(ref)
+		f.Line("if !%s.IsValid() {", expr)
+		f.Indent()
+		f.Line("violations = append(violations, Violation{Field: %s, Message: %q})", path, "must be one of "+strings.Join(enum.Members, ", "))
+		f.Dedent()
+		f.Line("}")
+	case g.Resolver.IsObject(ref), g.Resolver.IsUnion(ref):
+		f.Line("violations = append(violations, %s.validate(%s)...)", expr, path)
+	}
+}
@@ -1,26 +1,221 @@
 package ts
 
-import "github.com/printchard/scapi/spec"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/printchard/scapi/spec"
+)
+
+var tsIdentifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsPropertyName returns name as a property name, quoted unless it is an
+// identifier.
+func tsPropertyName(name string) string {
+	if tsIdentifierPattern.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+// tsPropertyAccess returns the expression accessing the property name of
+// expr.
+func tsPropertyAccess(expr, name string) string {
+	if tsIdentifierPattern.MatchString(name) {
+		return expr + "." + name
+	}
+	return fmt.Sprintf("%s[%q]", expr, name)
+}
 
 func (g *TsGenerator) generateQueryCreation(f *spec.Formatter, endpoint spec.Endpoint) {
 	f.Line("const queryParams = new URLSearchParams();")
 	for name, field := range endpoint.Input.Query {
+		if field.Default != nil {
+			f.Line(`queryParams.append("%s", String(input.query.%s ?? %s));`, name, name, tsValue(field.Default))
+			continue
+		}
 		f.Line("if (input.query.%s !== undefined) {", name)
 		f.Indent()
-		primType, _ := g.Resolver.PrimitiveOf(field.Ref)
-		switch primType {
-		case spec.Boolean:
-			f.Line(`queryParams.append("%s", String(input.query.%s));`, name, name)
-		case spec.Integer, spec.Float:
+		if field.Cardinality == spec.Multiple {
+			f.Line("for (const value of input.query.%s) {", name)
+			f.Indent()
+			f.Line(`queryParams.append("%s", String(value));`, name)
+			f.Dedent()
+			f.Line("}")
+		} else {
 			f.Line(`queryParams.append("%s", String(input.query.%s));`, name, name)
-		case spec.String:
-			f.Line(`queryParams.append("%s", input.query.%s);`, name, name)
 		}
 		f.Dedent()
 		f.Line("}")
 	}
 }
 
+func (g *TsGenerator) generateHeadersCreation(f *spec.Formatter, endpoint spec.Endpoint) {
+	f.Line("const headers: Record<string, string> = {};")
+	for name, field := range endpoint.Input.Headers {
+		value := tsPropertyAccess("input.headers", name)
+		switch {
+		case field.Default != nil:
+			f.Line(`headers["%s"] = String(%s ?? %s);`, name, value, tsValue(field.Default))
+		case field.Optional:
+			f.Line("if (%s !== undefined) {", value)
+			f.Indent()
+			f.Line(`headers["%s"] = String(%s);`, name, value)
+			f.Dedent()
+			f.Line("}")
+		default:
+			f.Line(`headers["%s"] = String(%s);`, name, value)
+		}
+	}
+}
+
+// generateBodyCreation emits code encoding the request body of endpoint as
+// its content type declares, and returns the expression holding it.
+func (g *TsGenerator) generateBodyCreation(f *spec.Formatter, endpoint spec.Endpoint) string {
+	switch endpoint.Input.Encoding() {
+	case spec.FormEncoding:
+		g.generateFormCreation(f, endpoint, "URLSearchParams")
+		return "form"
+	case spec.MultipartEncoding:
+		g.generateFormCreation(f, endpoint, "FormData")
+		return "form"
+	case spec.RawEncoding:
+		// Bytes are held as base64 encoded strings.
+		if primType, _ := g.Resolver.PrimitiveOf(*endpoint.Input.Body); primType == spec.Bytes {
+			return "Uint8Array.from(atob(input.body), (c) => c.charCodeAt(0))"
+		}
+		return "input.body"
+	default:
+		return "JSON.stringify(input.body)"
+	}
+}
+
+// generateFormCreation emits the form or multipart body of endpoint as a
+// new instance of class, URLSearchParams or FormData. Files are appended as
+// they are; other values as strings.
+func (g *TsGenerator) generateFormCreation(f *spec.Formatter, endpoint spec.Endpoint, class string) {
+	body, _ := g.Resolver.ObjectOf(*endpoint.Input.Body)
+	f.Line("const form = new %s();", class)
+	for name, field := range body.Fields {
+		value := tsPropertyAccess("input.body", name)
+		part := func(expr string) string {
+			if primType, _ := g.Resolver.PrimitiveOf(field.Ref); primType == spec.File {
+				return expr
+			}
+			return "String(" + expr + ")"
+		}
+		switch {
+		case field.Cardinality == spec.Multiple:
+			if field.Optional {
+				f.Line("for (const value of %s ?? []) {", value)
+			} else {
+				f.Line("for (const value of %s) {", value)
+			}
+			f.Indent()
+			f.Line(`form.append("%s", %s);`, name, part("value"))
+			f.Dedent()
+			f.Line("}")
+		case field.Optional:
+			f.Line("if (%s !== undefined) {", value)
+			f.Indent()
+			f.Line(`form.append("%s", %s);`, name, part(value))
+			f.Dedent()
+			f.Line("}")
+		default:
+			f.Line(`form.append("%s", %s);`, name, part(value))
+		}
+	}
+}
+
+// generateErrorCheck emits code throwing an HTTPError if response failed. The
+// error body is decoded as JSON, unless it is empty and left undefined, as
+// failures such as those of proxies need not match the declared responses.
+func (g *TsGenerator) generateErrorCheck(f *spec.Formatter) {
+	f.Line("if (!response.ok) {")
+	f.Indent()
+	f.Line("const errorText = await response.text();")
+	f.Line("const errorBody = errorText === \"\" ? undefined : JSON.parse(errorText);")
+	f.Line("throw new HTTPError(response.status, errorBody, response.headers);")
+	f.Dedent()
+	f.Line("}")
+}
+
+// generateStreamFetch emits the body of a client method streaming the
+// events of resp. The request is sent again whenever the stream reconnects,
+// with the ID of the last event received.
+func (g *TsGenerator) generateStreamFetch(f *spec.Formatter, resp spec.Response, init []string) {
+	f.Line("const connect = async (lastEventID?: string): Promise<Response> => {")
+	f.Indent()
+	f.Line("if (lastEventID !== undefined) {")
+	f.Indent()
+	f.Line(`headers["Last-Event-ID"] = lastEventID;`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("const response = await fetch(reqURL, { %s });", strings.Join(init, ", "))
+	g.generateErrorCheck(f)
+	f.Line("return response;")
+	f.Dedent()
+	f.Line("};")
+	f.Line("yield* readStream<%s>(connect, %q);", g.generateTsType(*resp.Ref), resp.Stream)
+}
+
+// responseHeaderValue returns the expression reading the response header
+// name as a value of the type of field.
+func (g *TsGenerator) responseHeaderValue(name string, field spec.Field) string {
+	convert := func(expr string) string {
+		primType, _ := g.Resolver.PrimitiveOf(field.Ref)
+		switch primType {
+		case spec.Integer, spec.Float:
+			expr = fmt.Sprintf("Number(%s)", expr)
+		case spec.Boolean:
+			expr = fmt.Sprintf(`%s === "true"`, expr)
+		}
+		if g.Resolver.IsAlias(field.Ref) {
+			expr = fmt.Sprintf("(%s) as %s", expr, g.generateTsType(field.Ref))
+		}
+		return expr
+	}
+	if field.Optional {
+		return fmt.Sprintf(`response.headers.has("%s") ? %s : undefined`, name, convert(fmt.Sprintf(`response.headers.get("%s")!`, name)))
+	}
+	return convert(fmt.Sprintf(`requireHeader(response, "%s")`, name))
+}
+
+// responseBodyType returns the TS type of the body of resp.
+func (g *TsGenerator) responseBodyType(resp spec.Response) string {
+	if resp.IsBinary() {
+		return "Binary"
+	}
+	if resp.IsEmpty() {
+		return "void"
+	}
+	return g.generateTsType(*resp.Ref)
+}
+
+// resultType returns the type a client method resolves to: the success
+// response body, along with the response headers if it declares any.
+func (g *TsGenerator) resultType(endpoint spec.Endpoint) string {
+	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
+	bodyType := g.responseBodyType(*successResp)
+	if len(successResp.Headers) == 0 {
+		return bodyType
+	}
+	headers := ""
+	for name, field := range successResp.Headers {
+		optionalMark := ""
+		if field.Optional {
+			optionalMark = "?"
+		}
+		headers += fmt.Sprintf(" %s%s: %s;", tsPropertyName(name), optionalMark, g.generateFieldTsType(field))
+	}
+	if successResp.IsEmpty() {
+		return fmt.Sprintf("{ headers: {%s } }", headers)
+	}
+	return fmt.Sprintf("{ body: %s; headers: {%s } }", bodyType, headers)
+}
+
 func (g *TsGenerator) generatePathCreation(f *spec.Formatter, endpoint spec.Endpoint) {
 	comps := endpoint.Path.Components()
 	if len(comps) == 0 {
@@ -41,9 +236,43 @@ func (g *TsGenerator) generatePathCreation(f *spec.Formatter, endpoint spec.Endp
 	f.Line("")
 }
 
+// cookieDocLines documents the cookies endpoint expects. Browsers do not let
+// fetch set the Cookie header, so the client sends whatever cookies the
+// browser holds for the API's origin instead of taking them as input.
+func cookieDocLines(endpoint spec.Endpoint) []string {
+	if endpoint.Input == nil || len(endpoint.Input.Cookies) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(endpoint.Input.Cookies))
+	for name := range endpoint.Input.Cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{"", "Sends the browser's cookies for the API, which must include:"}
+	for _, name := range names {
+		field := endpoint.Input.Cookies[name]
+		typ := field.Ref.String()
+		if field.Optional {
+			typ += ", optional"
+		}
+		lines = append(lines, fmt.Sprintf("- `%s` (%s)", name, typ))
+	}
+	return lines
+}
+
 func (g *TsGenerator) generateClientMethod(f *spec.Formatter, endpoint spec.Endpoint) {
 	methodName := endpoint.Name
-	f.Partial("async %s(", methodName)
+	docLines := cookieDocLines(endpoint)
+	if endpoint.Doc == "" && len(docLines) > 0 {
+		docLines = docLines[1:]
+	}
+	generateDocComment(f, endpoint.Doc, append(docLines, deprecatedTags(endpoint.Deprecated)...)...)
+	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
+	if successResp.IsStream() {
+		f.Partial("async *%s(", methodName)
+	} else {
+		f.Partial("async %s(", methodName)
+	}
 
 	if endpoint.Input != nil {
 		f.Partial("input: {\n")
@@ -53,7 +282,8 @@ func (g *TsGenerator) generateClientMethod(f *spec.Formatter, endpoint spec.Endp
 			f.Line("params: {")
 			f.Indent()
 			for paramName, field := range endpoint.Input.Params {
-				tsType := g.generateTsType(field.Ref)
+				generateDocComment(f, field.Doc, fieldDocTags(field)...)
+				tsType := g.generateFieldTsType(field)
 				optionalMark := ""
 				if field.Optional {
 					optionalMark = "?"
@@ -67,7 +297,8 @@ func (g *TsGenerator) generateClientMethod(f *spec.Formatter, endpoint spec.Endp
 			f.Line("query: {")
 			f.Indent()
 			for queryName, field := range endpoint.Input.Query {
-				tsType := g.generateTsType(field.Ref)
+				generateDocComment(f, field.Doc, fieldDocTags(field)...)
+				tsType := g.generateFieldTsType(field)
 				optionalMark := ""
 				if field.Optional {
 					optionalMark = "?"
@@ -77,14 +308,31 @@ func (g *TsGenerator) generateClientMethod(f *spec.Formatter, endpoint spec.Endp
 			f.Dedent()
 			f.Line("};")
 		}
+		if len(endpoint.Input.Headers) > 0 {
+			f.Line("headers: {")
+			f.Indent()
+			for headerName, field := range endpoint.Input.Headers {
+				generateDocComment(f, field.Doc, fieldDocTags(field)...)
+				optionalMark := ""
+				if field.Optional {
+					optionalMark = "?"
+				}
+				f.Line("%s%s: %s;", tsPropertyName(headerName), optionalMark, g.generateFieldTsType(field))
+			}
+			f.Dedent()
+			f.Line("};")
+		}
 		if endpoint.Input.Body != nil {
 			tsType := g.generateTsType(*endpoint.Input.Body)
 			f.Line("body: %s;", tsType)
 		}
 		f.Dedent()
 	}
-	successResp := g.Resolver.ResolveSuccessResponse(endpoint)
-	f.Line("}): Promise<%s> {", g.generateTsType(*successResp.Ref))
+	if successResp.IsStream() {
+		f.Line("}): AsyncGenerator<%s> {", g.generateTsType(*successResp.Ref))
+	} else {
+		f.Line("}): Promise<%s> {", g.resultType(endpoint))
+	}
 	f.Indent()
 	g.generateQueryCreation(f, endpoint)
 	g.generatePathCreation(f, endpoint)
@@ -95,16 +343,339 @@ func (g *TsGenerator) generateClientMethod(f *spec.Formatter, endpoint spec.Endp
 		f.Line("const reqURL = `${this.baseURL}${path}`;")
 	}
 
-	f.Line("const response = await fetch(reqURL);")
-	f.Line("if (!response.ok) {")
+	var init []string
+	hasBody := endpoint.Input != nil && endpoint.Input.Body != nil
+	if hasBody {
+		body := g.generateBodyCreation(f, endpoint)
+		init = append(init, fmt.Sprintf("method: %q", endpoint.Method), "body: "+body)
+	}
+	// The browser sets the multipart Content-Type, boundary included.
+	setContentType := hasBody && !endpoint.Input.IsMultipart()
+	if setContentType || successResp.IsStream() || (endpoint.Input != nil && len(endpoint.Input.Headers) > 0) {
+		g.generateHeadersCreation(f, endpoint)
+		if setContentType {
+			f.Line(`headers["Content-Type"] = %q;`, endpoint.Input.ContentType)
+		}
+		if successResp.IsStream() {
+			f.Line(`headers["Accept"] = %q;`, successResp.Stream.ContentType())
+		}
+		init = append(init, "headers")
+	}
+	if endpoint.Input != nil && len(endpoint.Input.Cookies) > 0 {
+		init = append(init, `credentials: "include"`)
+	}
+	if successResp.IsStream() {
+		g.generateStreamFetch(f, *successResp, init)
+		f.Dedent()
+		f.Line("}")
+		f.Line("")
+		return
+	}
+	if len(init) > 0 {
+		f.Line("const response = await fetch(reqURL, { %s });", strings.Join(init, ", "))
+	} else {
+		f.Line("const response = await fetch(reqURL);")
+	}
+	g.generateErrorCheck(f)
+
+	if successResp.IsBinary() {
+		f.Line("const responseBody: Binary = {")
+		f.Indent()
+		f.Line(`contentType: response.headers.get("Content-Type") ?? %q,`, successResp.ContentType)
+		f.Line(`contentLength: response.headers.has("Content-Length") ? Number(response.headers.get("Content-Length")) : null,`)
+		f.Line("stream: response.body,")
+		f.Line("blob: () => response.blob(),")
+		f.Dedent()
+		f.Line("};")
+	} else if !successResp.IsEmpty() {
+		f.Line("const responseBody = await response.json();")
+	}
+	body := "responseBody"
+	if !successResp.IsBinary() {
+		body += " as " + g.responseBodyType(*successResp)
+	}
+	switch {
+	case len(successResp.Headers) == 0 && successResp.IsEmpty():
+		f.Line("return;")
+	case len(successResp.Headers) == 0:
+		f.Line("return %s;", body)
+	default:
+		f.Line("return {")
+		f.Indent()
+		if !successResp.IsEmpty() {
+			f.Line("body: %s,", body)
+		}
+		f.Line("headers: {")
+		f.Indent()
+		for name, field := range successResp.Headers {
+			f.Line("%s: %s,", tsPropertyName(name), g.responseHeaderValue(name, field))
+		}
+		f.Dedent()
+		f.Line("},")
+		f.Dedent()
+		f.Line("};")
+	}
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+}
+
+// generateRequireHeader emits requireHeader, which reads the value of a
+// required response header and throws if the response lacks it.
+func (g *TsGenerator) generateRequireHeader(f *spec.Formatter) {
+	f.Line("function requireHeader(response: Response, name: string): string {")
+	f.Indent()
+	f.Line("const value = response.headers.get(name);")
+	f.Line("if (value === null) {")
+	f.Indent()
+	f.Line("throw new Error(`missing ${name} header`);")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return value;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+}
+
+// generateReadStream emits readStream, which client methods of streamed
+// responses delegate to. Stopping the iteration cancels the response.
+func (g *TsGenerator) generateReadStream(f *spec.Formatter) {
+	f.Line("/**")
+	f.Line(" * Reads the events of a streamed response. Server-sent event streams")
+	f.Line(" * reconnect when their connection drops, resuming after the last event")
+	f.Line(" * received.")
+	f.Line(" */")
+	f.Line(`async function* readStream<T>(connect: (lastEventID?: string) => Promise<Response>, format: "sse" | "ndjson"): AsyncGenerator<T> {`)
+	f.Indent()
+	f.Line("let response = await connect();")
+	f.Line("let lastEventID: string | undefined;")
+	f.Line("let retry = 3000;")
+	f.Line("for (;;) {")
+	f.Indent()
+	f.Line("const reader = response.body!.pipeThrough(new TextDecoderStream()).getReader();")
+	f.Line(`let buffer = "";`)
+	f.Line("let data: string[] = [];")
+	f.Line("try {")
+	f.Indent()
+	f.Line("for (;;) {")
+	f.Indent()
+	f.Line("let chunk: ReadableStreamReadResult<string>;")
+	f.Line("try {")
+	f.Indent()
+	f.Line("chunk = await reader.read();")
+	f.Dedent()
+	f.Line("} catch (err) {")
+	f.Indent()
+	f.Line(`if (format === "ndjson") {`)
+	f.Indent()
+	f.Line("throw err;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("break;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if (chunk.done) {")
+	f.Indent()
+	f.Line("break;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("buffer += chunk.value;")
+	f.Line(`const lines = buffer.split("\n");`)
+	f.Line("buffer = lines.pop()!;")
+	f.Line("for (const rawLine of lines) {")
+	f.Indent()
+	f.Line(`const line = rawLine.endsWith("\r") ? rawLine.slice(0, -1) : rawLine;`)
+	f.Line(`if (format === "ndjson") {`)
+	f.Indent()
+	f.Line(`if (line.trim() !== "") {`)
 	f.Indent()
-	f.Line("const errorBody = await response.json();")
-	f.Line("throw new HTTPError(response.status, errorBody);")
+	f.Line("yield JSON.parse(line) as T;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("continue;")
+	f.Dedent()
+	f.Line("}")
+	f.Line(`if (line === "") {`)
+	f.Indent()
+	f.Line("if (data.length > 0) {")
+	f.Indent()
+	f.Line(`yield JSON.parse(data.join("\n")) as T;`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("data = [];")
+	f.Line("continue;")
 	f.Dedent()
 	f.Line("}")
+	f.Line(`const colon = line.indexOf(":");`)
+	f.Line("const field = colon < 0 ? line : line.slice(0, colon);")
+	f.Line(`let value = colon < 0 ? "" : line.slice(colon + 1);`)
+	f.Line(`if (value.startsWith(" ")) {`)
+	f.Indent()
+	f.Line("value = value.slice(1);")
+	f.Dedent()
+	f.Line("}")
+	f.Line(`if (field === "data") {`)
+	f.Indent()
+	f.Line("data.push(value);")
+	f.Dedent()
+	f.Line(`} else if (field === "id") {`)
+	f.Indent()
+	f.Line("lastEventID = value;")
+	f.Dedent()
+	f.Line(`} else if (field === "retry" && /^\d+$/.test(value)) {`)
+	f.Indent()
+	f.Line("retry = Number(value);")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("} finally {")
+	f.Indent()
+	f.Line("await reader.cancel().catch(() => {});")
+	f.Dedent()
+	f.Line("}")
+	f.Line(`if (format === "ndjson") {`)
+	f.Indent()
+	f.Line(`if (buffer.trim() !== "") {`)
+	f.Indent()
+	f.Line("yield JSON.parse(buffer) as T;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("return;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("await new Promise((resolve) => setTimeout(resolve, retry));")
+	f.Line("response = await connect(lastEventID);")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+}
 
-	f.Line("const responseBody = await response.json();")
-	f.Line("return responseBody as %s;", g.generateTsType(*successResp.Ref))
+// generateChannelClass emits the Channel class client methods of channels
+// resolve to.
+func (g *TsGenerator) generateChannelClass(f *spec.Formatter) {
+	f.Line("/**")
+	f.Line(" * A WebSocket connection exchanging JSON messages, sending messages of")
+	f.Line(" * type S and receiving messages of type R.")
+	f.Line(" */")
+	f.Line("export class Channel<S, R> {")
+	f.Indent()
+	f.Line("private socket: WebSocket;")
+	f.Line("private received: R[] = [];")
+	f.Line("private waiting: { resolve: (message: R) => void; reject: (err: Error) => void }[] = [];")
+	f.Line("private closed = false;")
+	f.Line("")
+	f.Line("constructor(socket: WebSocket) {")
+	f.Indent()
+	f.Line("this.socket = socket;")
+	f.Line(`socket.addEventListener("message", (event) => {`)
+	f.Indent()
+	f.Line("const message = JSON.parse(event.data) as R;")
+	f.Line("const waiter = this.waiting.shift();")
+	f.Line("if (waiter) {")
+	f.Indent()
+	f.Line("waiter.resolve(message);")
+	f.Dedent()
+	f.Line("} else {")
+	f.Indent()
+	f.Line("this.received.push(message);")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("});")
+	f.Line(`socket.addEventListener("close", () => {`)
+	f.Indent()
+	f.Line("this.closed = true;")
+	f.Line("for (const waiter of this.waiting.splice(0)) {")
+	f.Indent()
+	f.Line(`waiter.reject(new Error("channel closed"));`)
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("});")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("/** Opens a channel to url, resolving once it is connected. */")
+	f.Line("static open<S, R>(url: string): Promise<Channel<S, R>> {")
+	f.Indent()
+	f.Line("return new Promise((resolve, reject) => {")
+	f.Indent()
+	f.Line("const socket = new WebSocket(url);")
+	f.Line("const channel = new Channel<S, R>(socket);")
+	f.Line(`socket.addEventListener("open", () => resolve(channel), { once: true });`)
+	f.Line(`socket.addEventListener("error", () => reject(new Error(` + "`cannot connect to ${url}`" + `)), { once: true });`)
+	f.Dedent()
+	f.Line("});")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("send(message: S): void {")
+	f.Indent()
+	f.Line("this.socket.send(JSON.stringify(message));")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("/** Waits for the next message. Rejects once the channel is closed. */")
+	f.Line("receive(): Promise<R> {")
+	f.Indent()
+	f.Line("if (this.received.length > 0) {")
+	f.Indent()
+	f.Line("return Promise.resolve(this.received.shift()!);")
+	f.Dedent()
+	f.Line("}")
+	f.Line("if (this.closed) {")
+	f.Indent()
+	f.Line(`return Promise.reject(new Error("channel closed"));`)
+	f.Dedent()
+	f.Line("}")
+	f.Line("return new Promise((resolve, reject) => this.waiting.push({ resolve, reject }));")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("/** Yields the messages received until the channel is closed. */")
+	f.Line("async *[Symbol.asyncIterator](): AsyncGenerator<R> {")
+	f.Indent()
+	f.Line("while (this.received.length > 0 || !this.closed) {")
+	f.Indent()
+	f.Line("let message: R;")
+	f.Line("try {")
+	f.Indent()
+	f.Line("message = await this.receive();")
+	f.Dedent()
+	f.Line("} catch {")
+	f.Indent()
+	f.Line("return;")
+	f.Dedent()
+	f.Line("}")
+	f.Line("yield message;")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+	f.Line("close(): void {")
+	f.Indent()
+	f.Line("this.socket.close();")
+	f.Dedent()
+	f.Line("}")
+	f.Dedent()
+	f.Line("}")
+	f.Line("")
+}
+
+// generateChannelMethod emits the client method opening channel. The
+// WebSocket URL is the base URL with its http scheme replaced by ws.
+func (g *TsGenerator) generateChannelMethod(f *spec.Formatter, channel spec.Channel) {
+	generateDocComment(f, channel.Doc, deprecatedTags(channel.Deprecated)...)
+	f.Line("%s(): Promise<Channel<%s, %s>> {", channel.Name, g.generateTsType(channel.Client), g.generateTsType(channel.Server))
+	f.Indent()
+	f.Line("return Channel.open(`${this.baseURL.replace(/^http/, \"ws\")}%s`);", channel.Path.String())
 	f.Dedent()
 	f.Line("}")
 	f.Line("")
@@ -112,6 +683,16 @@ func (g *TsGenerator) generateClientMethod(f *spec.Formatter, endpoint spec.Endp
 
 func (g *TsGenerator) GenerateClient() string {
 	formatter := spec.NewFormatter()
+	if g.hasStreamResponse() {
+		g.generateReadStream(formatter)
+	}
+	if g.hasRequiredResponseHeader() {
+		g.generateRequireHeader(formatter)
+	}
+	if len(g.API.Channels) > 0 {
+		g.generateChannelClass(formatter)
+	}
+	generateDocComment(formatter, g.API.Doc)
 	formatter.Line("export class APIClient {")
 	formatter.Indent()
 	formatter.Line("baseURL: string;")
@@ -125,6 +706,9 @@ func (g *TsGenerator) GenerateClient() string {
 	for _, endpoint := range g.API.Endpoints {
 		g.generateClientMethod(formatter, endpoint)
 	}
+	for _, channel := range g.API.Channels {
+		g.generateChannelMethod(formatter, channel)
+	}
 
 	formatter.Dedent()
 	formatter.Line("}")
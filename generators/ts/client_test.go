@@ -0,0 +1,33 @@
+package ts_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+	"github.com/printchard/scapi/generators/ts"
+)
+
+func TestErrorBodyIsReadAsText(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Users
+
+type User { id: uuid }
+
+endpoint GET /users/{id} getUser {
+  params { id: uuid }
+  responses {
+    200 User
+    404 string
+  }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	code := ts.NewTsGenerator(api).GenerateClient()
+	if strings.Contains(code, "const errorBody = await response.json()") {
+		t.Errorf("expected the error body not to be decoded as JSON directly:\n%s", code)
+	}
+	if !strings.Contains(code, `const errorBody = errorText === "" ? undefined : JSON.parse(errorText);`) {
+		t.Errorf("expected an empty error body to be left undefined:\n%s", code)
+	}
+}
@@ -1,11 +1,18 @@
 package ts
 
-import "github.com/printchard/scapi/spec"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/printchard/scapi/spec"
+)
 
 type TsGenerator struct {
 	API      *spec.APISpec
 	Resolver spec.TypeResolver
 	Name     string
+	// Naming maps the qualified names of packaged types to TS names.
+	Naming spec.Naming
 }
 
 func NewTsGenerator(api *spec.APISpec) *TsGenerator {
@@ -15,17 +22,74 @@ func NewTsGenerator(api *spec.APISpec) *TsGenerator {
 	}
 }
 
+// hasBinaryResponse reports whether any endpoint responds with a binary
+// body.
+func (g *TsGenerator) hasBinaryResponse() bool {
+	for _, endpoint := range g.API.Endpoints {
+		if g.Resolver.ResolveSuccessResponse(endpoint).IsBinary() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasStreamResponse reports whether any endpoint streams its success
+// response.
+func (g *TsGenerator) hasStreamResponse() bool {
+	for _, endpoint := range g.API.Endpoints {
+		if g.Resolver.ResolveSuccessResponse(endpoint).IsStream() {
+			return true
+		}
+	}
+	return false
+}
+
+// hasRequiredResponseHeader reports whether the success response of any
+// endpoint declares a header that is not optional.
+func (g *TsGenerator) hasRequiredResponseHeader() bool {
+	for _, endpoint := range g.API.Endpoints {
+		for _, field := range g.Resolver.ResolveSuccessResponse(endpoint).Headers {
+			if !field.Optional {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generateBinaryTypeDef emits the Binary type client methods of binary
+// responses resolve to.
+func (g *TsGenerator) generateBinaryTypeDef() string {
+	formatter := spec.NewFormatter()
+	formatter.Line("/** A binary response body, streamed rather than decoded as JSON. */")
+	formatter.Line("export interface Binary {")
+	formatter.Indent()
+	formatter.Line("contentType: string;")
+	formatter.Line("/** The length of the body in bytes, or null if unknown. */")
+	formatter.Line("contentLength: number | null;")
+	formatter.Line("/** The body as a stream. Read either the stream or blob(), not both. */")
+	formatter.Line("stream: ReadableStream<Uint8Array> | null;")
+	formatter.Line("/** Reads the whole body. */")
+	formatter.Line("blob(): Promise<Blob>;")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
 func (g *TsGenerator) generateErrorTypeDef() string {
 	formatter := spec.NewFormatter()
 	formatter.Line("export class HTTPError extends Error {")
 	formatter.Indent()
 	formatter.Line("code: number;")
 	formatter.Line("body: unknown;")
-	formatter.Line("constructor(code: number, body: unknown) {")
+	formatter.Line("headers: Headers;")
+	formatter.Line("constructor(code: number, body: unknown, headers: Headers) {")
 	formatter.Indent()
 	formatter.Line("super(`HTTP ${code}: ${body}`);")
 	formatter.Line("this.code = code;")
 	formatter.Line("this.body = body;")
+	formatter.Line("this.headers = headers;")
 	formatter.Dedent()
 	formatter.Line("}")
 	formatter.Dedent()
@@ -34,31 +98,157 @@ func (g *TsGenerator) generateErrorTypeDef() string {
 	return formatter.String()
 }
 
+func (g *TsGenerator) generateFieldTsType(field spec.Field) string {
+	tsType := g.generateTsType(field.Ref)
+	switch field.Cardinality {
+	case spec.Multiple:
+		return tsType + "[]"
+	case spec.Map:
+		return fmt.Sprintf("Record<%s, %s>", g.generateTsType(*field.Key), tsType)
+	default:
+		return tsType
+	}
+}
+
+func primitiveTsType(primType spec.PrimitiveType) string {
+	switch primType {
+	case spec.String, spec.DateTime, spec.Date, spec.UUID, spec.Email, spec.URL, spec.Decimal:
+		return "string"
+	case spec.Bytes:
+		// Bytes are sent as base64 encoded strings.
+		return "string"
+	case spec.Int64:
+		// Numbers cannot represent every int64, so they are sent as strings
+		// of decimal digits.
+		return "string"
+	case spec.Integer, spec.Float:
+		return "number"
+	case spec.Boolean:
+		return "boolean"
+	case spec.File:
+		// File extends Blob, so either may be uploaded.
+		return "Blob"
+	default:
+		return "any"
+	}
+}
+
+// generateDocComment emits doc, if any, followed by lines such as block tags
+// as a TSDoc comment.
+func generateDocComment(f *spec.Formatter, doc string, lines ...string) {
+	if doc != "" {
+		lines = append(strings.Split(doc, "\n"), lines...)
+	}
+	switch len(lines) {
+	case 0:
+		return
+	case 1:
+		f.Line("/** %s */", lines[0])
+		return
+	}
+	f.Line("/**")
+	for _, line := range lines {
+		if line == "" {
+			f.Line(" *")
+		} else {
+			f.Line(" * %s", line)
+		}
+	}
+	f.Line(" */")
+}
+
+func deprecatedTags(deprecated *spec.Deprecation) []string {
+	if deprecated == nil {
+		return nil
+	}
+	return []string{"@deprecated " + deprecated.String()}
+}
+
+// fieldDocTags returns the TSDoc block tags describing field.
+func fieldDocTags(field spec.Field) []string {
+	var tags []string
+	if field.Default != nil {
+		tags = append(tags, "@default "+tsValue(field.Default))
+	}
+	return append(tags, deprecatedTags(field.Deprecated)...)
+}
+
+// tsValue returns the TypeScript literal for value. Enum members are string
+// literals.
+func tsValue(value *spec.Value) string {
+	switch {
+	case value.Kind == spec.StringValue:
+		return fmt.Sprintf("%q", value.Literal)
+	case value.Kind == spec.SymbolValue && value.Literal != "true" && value.Literal != "false":
+		return fmt.Sprintf("%q", value.Literal)
+	default:
+		return value.Literal
+	}
+}
+
+func (g *TsGenerator) generateAliasTypeDef(typeName string, alias *spec.AliasType) string {
+	primType, _ := g.Resolver.PrimitiveOf(alias.Target)
+	return fmt.Sprintf("export type %s = %s & { readonly __brand: %q };\n", typeName, primitiveTsType(primType), typeName)
+}
+
 func (g *TsGenerator) generateTsType(typeRef spec.TypeRef) string {
+	if typeRef.IsArray() {
+		return g.generateTsType(*typeRef.Elem) + "[]"
+	}
+	if typeRef.TypeParam {
+		return typeRef.Name
+	}
+	if g.Resolver.IsAlias(typeRef) {
+		return g.Naming.TypeName(typeRef.Name)
+	}
 	if g.Resolver.IsPrimitive(typeRef) {
 		primType, _ := g.Resolver.PrimitiveOf(typeRef)
-		switch primType {
-		case spec.String:
-			return "string"
-		case spec.Integer, spec.Float:
-			return "number"
-		case spec.Boolean:
-			return "boolean"
-		default:
-			return "any"
+		return primitiveTsType(primType)
+	} else if g.Resolver.IsObject(typeRef) || g.Resolver.IsEnum(typeRef) || g.Resolver.IsUnion(typeRef) {
+		if len(typeRef.Args) > 0 {
+			args := make([]string, len(typeRef.Args))
+			for i, arg := range typeRef.Args {
+				args[i] = g.generateTsType(arg)
+			}
+			return g.Naming.TypeName(typeRef.Name) + "<" + strings.Join(args, ", ") + ">"
 		}
-	} else if g.Resolver.IsObject(typeRef) {
-		return typeRef.Name
+		return g.Naming.TypeName(typeRef.Name)
 	}
 	return "any"
 }
 
-func (g *TsGenerator) generateObjectTypeDef(typeName string, obj *spec.ObjectType) string {
+func (g *TsGenerator) generateObjectTypeDef(typeName string, typ *spec.Type) string {
+	obj := typ.ObjectType
 	formatter := spec.NewFormatter()
-	formatter.Line("export interface %s {", typeName)
+	typeParams := ""
+	if len(obj.TypeParams) > 0 {
+		typeParams = "<" + strings.Join(obj.TypeParams, ", ") + ">"
+	}
+	extends := ""
+	inherited := make(map[string]bool)
+	if len(obj.Parents) > 0 {
+		parents := make([]string, len(obj.Parents))
+		for i, parent := range obj.Parents {
+			parents[i] = g.generateTsType(parent)
+			parentObj, _ := g.Resolver.ObjectOf(parent)
+			for fieldName := range parentObj.Fields {
+				inherited[fieldName] = true
+			}
+		}
+		extends = " extends " + strings.Join(parents, ", ")
+	}
+	var notes []string
+	if obj.Derivation != nil {
+		notes = append(notes, fmt.Sprintf("Derived from %s.", obj.Derivation))
+	}
+	generateDocComment(formatter, typ.Doc, append(notes, deprecatedTags(typ.Deprecated)...)...)
+	formatter.Line("export interface %s%s%s {", typeName, typeParams, extends)
 	formatter.Indent()
 	for fieldName, field := range obj.Fields {
-		tsType := g.generateTsType(field.Ref)
+		if inherited[fieldName] {
+			continue
+		}
+		tsType := g.generateFieldTsType(field)
 		optionalMark := ""
 		if field.Optional {
 			optionalMark = "?"
@@ -67,9 +257,7 @@ func (g *TsGenerator) generateObjectTypeDef(typeName string, obj *spec.ObjectTyp
 		if field.Nullable {
 			nullableMark = " | null"
 		}
-		if field.Cardinality == spec.Multiple {
-			tsType = tsType + "[]"
-		}
+		generateDocComment(formatter, field.Doc, fieldDocTags(field)...)
 		formatter.Line("%s%s: %s%s;", fieldName, optionalMark, tsType, nullableMark)
 	}
 	formatter.Dedent()
@@ -77,14 +265,74 @@ func (g *TsGenerator) generateObjectTypeDef(typeName string, obj *spec.ObjectTyp
 	return formatter.String()
 }
 
+func (g *TsGenerator) generateEnumTypeDef(typeName string, enum *spec.EnumType) string {
+	formatter := spec.NewFormatter()
+	literals := make([]string, len(enum.Members))
+	for i, member := range enum.Members {
+		literals[i] = fmt.Sprintf("%q", member)
+	}
+	formatter.Line("export type %s = %s;", typeName, strings.Join(literals, " | "))
+	formatter.Line("export const %sValues: readonly %s[] = [%s];", typeName, typeName, strings.Join(literals, ", "))
+	return formatter.String()
+}
+
+func (g *TsGenerator) generateUnionTypeDef(typeName string, union *spec.UnionType) string {
+	formatter := spec.NewFormatter()
+	variants := make([]string, len(union.Variants))
+	for i, variant := range union.Variants {
+		variants[i] = fmt.Sprintf("(%s & { %s: %q })", g.Naming.TypeName(variant.Name), union.Discriminator, spec.VariantTag(variant))
+	}
+	formatter.Line("export type %s =", typeName)
+	formatter.Indent()
+	for i, variant := range variants {
+		if i == len(variants)-1 {
+			formatter.Line("| %s;", variant)
+		} else {
+			formatter.Line("| %s", variant)
+		}
+	}
+	formatter.Dedent()
+	return formatter.String()
+}
+
 func (g *TsGenerator) GenerateTypeDefs() string {
 	result := ""
-	for typeName, typ := range g.API.Types {
-		if typ.Kind != spec.Object {
-			continue
+	for name, typ := range g.API.Types {
+		typeName := g.Naming.TypeName(name)
+		switch typ.Kind {
+		case spec.Enum:
+			// Enum members cannot carry their own TSDoc.
+			var notes []string
+			for _, member := range typ.EnumType.Members {
+				if deprecated, ok := typ.EnumType.Deprecated[member]; ok {
+					notes = append(notes, fmt.Sprintf("%q is deprecated: %s.", member, deprecated))
+				}
+			}
+			doc := spec.NewFormatter()
+			generateDocComment(doc, typ.Doc, append(notes, deprecatedTags(typ.Deprecated)...)...)
+			result += doc.String()
+		case spec.Union, spec.Alias:
+			doc := spec.NewFormatter()
+			generateDocComment(doc, typ.Doc, deprecatedTags(typ.Deprecated)...)
+			result += doc.String()
+		}
+		switch typ.Kind {
+		case spec.Object:
+			result += g.generateObjectTypeDef(typeName, typ)
+			result += g.generateValidateFunction(typeName, typ.ObjectType)
+		case spec.Enum:
+			result += g.generateEnumTypeDef(typeName, typ.EnumType)
+		case spec.Union:
+			result += g.generateUnionTypeDef(typeName, typ.UnionType)
+			result += g.generateUnionValidateFunction(typeName, typ.UnionType)
+		case spec.Alias:
+			result += g.generateAliasTypeDef(typeName, typ.AliasType)
 		}
-		result += g.generateObjectTypeDef(typeName, typ.ObjectType)
 	}
 	result += g.generateErrorTypeDef()
+	if g.hasBinaryResponse() {
+		result += g.generateBinaryTypeDef()
+	}
+	result += g.generateValidationTypeDefs()
 	return result
 }
@@ -0,0 +1,126 @@
+package ts_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/printchard/scapi/dsl"
+	"github.com/printchard/scapi/generators/ts"
+)
+
+func TestInt64IsString(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Ledger
+
+type Entry {
+  id: int64 @min(1)
+  parts: [int64]
+}
+
+endpoint GET /entries/{id} getEntry {
+  params { id: int64 }
+  responses {
+    200 Entry headers { "X-Total": int64 }
+  }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	gen := ts.NewTsGenerator(api)
+	code := gen.GenerateTypeDefs() + gen.GenerateClient()
+	for _, want := range []string{
+		"id: string;",
+		"parts: string[];",
+		"BigInt(value.id) < 1",
+		`"X-Total": requireHeader(response, "X-Total")`,
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestRichPrimitivesAreStrings(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`api Events
+
+type Event {
+  id: uuid
+  at: datetime
+  day: date
+  payload: bytes
+  count: int64
+  price: decimal
+  contact: email
+  link: url
+  days?: [date]
+}
+
+endpoint GET /events/{id} getEvent {
+  params { id: uuid }
+  query {
+    since?: datetime
+    day?: date
+    token?: bytes
+  }
+  headers { "X-Contact": email }
+  responses {
+    200 Event headers { Location: url }
+  }
+}
+
+endpoint POST /events createEvent {
+  body Event
+  responses { 201 Event }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	gen := ts.NewTsGenerator(api)
+	code := gen.GenerateTypeDefs() + gen.GenerateClient()
+	for _, field := range []string{"id", "at", "day", "payload", "price", "contact", "link"} {
+		if want := "  " + field + ": string;"; !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, code)
+		}
+	}
+	if !strings.Contains(code, "days?: string[];") {
+		t.Errorf("expected days to be a list of strings:\n%s", code)
+	}
+}
+
+func TestDocComments(t *testing.T) {
+	api, err := dsl.NewTranslatorFromString(`/// The user service.
+api Users
+
+/// A registered user.
+///
+/// Users sign up with an email.
+@deprecated("use Account", since="2.0")
+type User {
+  /// The user's unique id.
+  /// Never reused.
+  id: uuid
+  /// Old login name.
+  login?: string @deprecated
+}
+
+/// Fetches a user.
+endpoint GET /users/{id} getUser {
+  params { id: uuid }
+  responses { 200 User }
+}`)
+	if err != nil {
+		t.Fatalf("expected valid spec, got error: %v", err)
+	}
+	gen := ts.NewTsGenerator(api)
+	code := gen.GenerateTypeDefs() + gen.GenerateClient()
+	for _, want := range []string{
+		"/**\n * A registered user.\n *\n * Users sign up with an email.\n * @deprecated use Account (since 2.0)\n */\nexport interface User {",
+		"  /**\n   * The user's unique id.\n   * Never reused.\n   */\n  id: string;",
+		"  /**\n   * Old login name.\n   * @deprecated do not use\n   */\n  login?: string;",
+		"/** The user service. */\nexport class APIClient {",
+		"  /** Fetches a user. */\n  async getUser(",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("expected generated code to contain %q:\n%s", want, code)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package ts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/printchard/scapi/spec"
+)
+
+// generateValidationTypeDefs emits the violation type returned by generated
+// validation functions and the helpers those functions share.
+func (g *TsGenerator) generateValidationTypeDefs() string {
+	formatter := spec.NewFormatter()
+	formatter.Line("export interface ValidationViolation {")
+	formatter.Indent()
+	formatter.Line("field: string;")
+	formatter.Line("message: string;")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	formatter.Line("function fieldPath(path: string, name: string): string {")
+	formatter.Indent()
+	formatter.Line("return path === \"\" ? name : `${path}.${name}`;")
+	formatter.Dedent()
+	formatter.Line("}")
+	formatter.Line("")
+	return formatter.String()
+}
+
+func patternConstName(typeName, fieldName string) string {
+	return "pattern" + typeName + strings.ToUpper(fieldName[:1]) + fieldName[1:]
+}
+
+// generateValidateFunction emits a validateX function for the object type
+// typeName. Violations are reported with the dotted path of the offending
+// field.
+func (g *TsGenerator) generateValidateFunction(typeName string, obj *spec.ObjectType) string {
+	formatter := spec.NewFormatter()
+	for fieldName, field := range obj.Fields {
+		for _, constraint := range field.Constraints {
+			if constraint.Kind == spec.Pattern {
+				formatter.Line("const %s = new RegExp(%q);", patternConstName(typeName, fieldName), constraint.Value)
+			}
+		}
+	}
+	typeParams := ""
+	if len(obj.TypeParams) > 0 {
+		typeParams = "<" + strings.Join(obj.TypeParams, ", ") + ">"
+	}
+	formatter.Line("export function validate%s%s(value: %s%s, path = \"\"): ValidationViolation[] {", typeName, typeParams, typeName, typeParams)
+	formatter.Indent()
+	formatter.Line("const violations: ValidationViolation[] = [];")
+	for fieldName, field := range obj.Fields {
+		g.generateFieldValidation(formatter, typeName, fieldName, field)
+	}
+	formatter.Line("return violations;")
+	formatter.Dedent()
+	formatter.Line("}")
+	return formatter.String()
+}
+
+func (g *TsGenerator) generateUnionValidateFunction(typeName string, union *spec.UnionType) string {
+	formatter := spec.NewFormatter()
+	formatter.Line("export function validate%s(value: %s, path = \"\"): ValidationViolation[] {", typeName, typeName)
+	formatter.Indent()
+	formatter.Line("switch (value.%s) {", union.Discriminator)
+	for _, variant := range union.Variants {
+		formatter.Line("case %q:", spec.VariantTag(variant))
+		formatter.Indent()
+		formatter.Line("return validate%s(value, path);", g.Naming.TypeName(variant.Name))
+		formatter.Dedent()
+	}
+	formatter.Line("}")
+	formatter.Line("return [];")
+	formatter.Dedent()
+	formatter.Line("}")
+	return formatter.String()
+}
+
+func constraintCheck(constraint spec.Constraint, expr, patternConst string) string {
+	switch constraint.Kind {
+	case spec.MinLength:
+		return fmt.Sprintf("Array.from(%s).length < %s", expr, constraint.Value)
+	case spec.MaxLength:
+		return fmt.Sprintf("Array.from(%s).length > %s", expr, constraint.Value)
+	case spec.Min:
+		return fmt.Sprintf("%s < %s", expr, constraint.Value)
+	case spec.Max:
+		return fmt.Sprintf("%s > %s", expr, constraint.Value)
+	case spec.Pattern:
+		return fmt.Sprintf("!%s.test(%s)", patternConst, expr)
+	case spec.MinItems:
+		return fmt.Sprintf("%s.length < %s", expr, constraint.Value)
+	case spec.MaxItems:
+		return fmt.Sprintf("%s.length > %s", expr, constraint.Value)
+	default:
+		return "false"
+	}
+}
+
+func (g *TsGenerator) generateFieldValidation(f *spec.Formatter, typeName, fieldName string, field spec.Field) {
+	if len(field.Constraints) == 0 && !g.needsValidation(field.Ref) {
+		return
+	}
+	expr := "value." + fieldName
+	path := fmt.Sprintf("fieldPath(path, %q)", fieldName)
+	guarded := field.Optional || field.Nullable
+	if guarded {
+		f.Line("if (%s !== undefined && %s !== null) {", expr, expr)
+		f.Indent()
+	}
+	primType, _ := g.Resolver.PrimitiveOf(field.Ref)
+	for _, constraint := range field.Constraints {
+		checked := expr
+		if primType == spec.Int64 && (constraint.Kind == spec.Min || constraint.Kind == spec.Max) {
+			// int64 values are strings, which are compared exactly as bigints.
+			checked = fmt.Sprintf("BigInt(%s)", expr)
+		}
+		f.Line("if (%s) {", constraintCheck(constraint, checked, patternConstName(typeName, fieldName)))
+		f.Indent()
+		f.Line("violations.push({ field: %s, message: %q });", path, constraint.Message())
+		f.Dedent()
+		f.Line("}")
+	}
+	if g.needsValidation(field.Ref) {
+		switch field.Cardinality {
+		case spec.Multiple:
+			f.Line("%s.forEach((item, i) => {", expr)
+			f.Indent()
+			g.generateValueValidation(f, field.Ref, "item", fmt.Sprintf("`${%s}[${i}]`", path), 1)
+			f.Dedent()
+			f.Line("});")
+		case spec.Map:
+			f.Line("for (const k in %s) {", expr)
+			f.Indent()
+			f.Line("const item = %s[k];", expr)
+			g.generateValueValidation(f, field.Ref, "item", fmt.Sprintf("`${%s}[${k}]`", path), 1)
+			f.Dedent()
+			f.Line("}")
+		default:
+			g.generateValueValidation(f, field.Ref, expr, path, 0)
+		}
+	}
+	if guarded {
+		f.Dedent()
+		f.Line("}")
+	}
+}
+
+// needsValidation reports whether values of ref can hold violations of their
+// own. Values of type parameters are not checked, as their type is unknown.
+func (g *TsGenerator) needsValidation(ref spec.TypeRef) bool {
+	if ref.IsArray() {
+		return g.needsValidation(*ref.Elem)
+	}
+	if ref.TypeParam {
+		return false
+	}
+	return g.Resolver.IsObject(ref) || g.Resolver.IsUnion(ref) || g.Resolver.IsEnum(ref)
+}
+
+func (g *TsGenerator) generateValueValidation(f *spec.Formatter, ref spec.TypeRef, expr, path string, depth int) {
+	switch {
+	case ref.IsArray():
+		index, item := fmt.Sprintf("i%d", depth), fmt.Sprintf("item%d", depth)
+		f.Line("%s.forEach((%s, %s) => {", expr, item, index)
+		f.Indent()
+		g.generateValueValidation(f, *ref.Elem, item, fmt.Sprintf("`${%s}[${%s}]`", path, index), depth+1)
+		f.Dedent()
+		f.Line("});")
+	case g.Resolver.IsEnum(ref):
+		enum, _ := g.Resolver.EnumOf(ref)
+		f.Line("if (!%sValues.includes(%s)) {", g.Naming.TypeName(ref.Name), expr)
+		f.Indent()
+		f.Line("violations.push({ field: %s, message: %q });", path, "must be one of "+strings.Join(enum.Members, ", "))
+		f.Dedent()
+		f.Line("}")
+	case g.Resolver.IsObject(ref), g.Resolver.IsUnion(ref):
+		f.Line("violations.push(...validate%s(%s, %s));", g.Naming.TypeName(ref.Name), expr, path)
+	}
+}
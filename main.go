@@ -6,10 +6,12 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/printchard/scapi/dsl"
 	"github.com/printchard/scapi/generators/golang"
 	"github.com/printchard/scapi/generators/ts"
+	"github.com/printchard/scapi/spec"
 )
 
 const usage = `Usage: scapi [command] [options] <input file>
@@ -21,7 +23,9 @@ Commands:
 Options:
 	-help       Show this help message
 	-i          Input file path
-	-o          Output file path (optional, defaults to stdout)`
+	-o          Output file path (optional, defaults to stdout)
+	-prefix     Prefix of the type names of a package, as package=Prefix
+	            (repeatable, defaults to the capitalized package name)`
 
 func main() {
 	log.SetFlags(0)
@@ -42,6 +46,15 @@ func main() {
 		fs := flag.NewFlagSet("generate", flag.ExitOnError)
 		inputFile := fs.String("i", "", "Input file path")
 		outputPath := fs.String("o", "", "Output file path")
+		naming := spec.Naming{Prefixes: make(map[string]string)}
+		fs.Func("prefix", "Prefix of the type names of a package, as package=Prefix", func(value string) error {
+			pkg, prefix, ok := strings.Cut(value, "=")
+			if !ok || pkg == "" {
+				return fmt.Errorf("expected package=Prefix, got %q", value)
+			}
+			naming.Prefixes[pkg] = prefix
+			return nil
+		})
 		outputFile := os.Stdout
 		if *outputPath != "" {
 			f, err := os.Create(*outputPath)
@@ -58,38 +71,39 @@ func main() {
 		}
 		lang := os.Args[2]
 		target := os.Args[3]
-		generateCode(lang, target, *inputFile, outputFile)
+		generateCode(lang, target, *inputFile, naming, outputFile)
 	case "validate":
 		fs := flag.NewFlagSet("validate", flag.ExitOnError)
 		inputFile := fs.String("i", "", "Input file path")
 		fs.Parse(os.Args[2:])
-		err := validateFile(*inputFile)
+		warnings, err := validateFile(*inputFile)
 		if err != nil {
 			log.Fatalf("Invalid File: %s", err)
 		}
+		if len(warnings) > 0 {
+			log.Printf("%d deprecated items are still referenced:", len(warnings))
+			for _, warning := range warnings {
+				log.Printf("  Warning: %s", warning)
+			}
+		}
 		log.Println("Validation successful")
 	default:
 		fmt.Fprintln(os.Stderr, usage)
 	}
 }
 
-func validateFile(inputPath string) error {
-	f, err := os.ReadFile(inputPath)
+// validateFile validates the input file, along with the files it imports,
+// and returns warnings about the deprecated items it still uses.
+func validateFile(inputPath string) ([]string, error) {
+	apiSpec, err := dsl.NewTranslatorFromFile(inputPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	_, err = dsl.NewTranslatorFromString(string(f))
-	return err
+	return apiSpec.DeprecationWarnings(), nil
 }
 
-func generateCode(lang, target, inputPath string, outputFile io.Writer) {
-	f, err := os.ReadFile(inputPath)
-	if err != nil {
-		log.Fatalf("Error reading file: %s", err)
-	}
-
-	apiSpec, err := dsl.NewTranslatorFromString(string(f))
+func generateCode(lang, target, inputPath string, naming spec.Naming, outputFile io.Writer) {
+	apiSpec, err := dsl.NewTranslatorFromFile(inputPath)
 	if err != nil {
 		log.Fatalf("Error parsing file: %s", err)
 	}
@@ -98,10 +112,18 @@ func generateCode(lang, target, inputPath string, outputFile io.Writer) {
 	if err != nil {
 		log.Fatalf("Validation error: %s", err)
 	}
+	if lang == "go" {
+		naming.Reserved = golang.ReservedNames
+	}
+	if err := apiSpec.CheckNaming(naming); err != nil {
+		log.Fatalf("Naming error: %s", err)
+	}
 
 	switch lang {
 	case "go":
 		gen := golang.NewGoGenerator(apiSpec)
+		gen.Naming = naming
+		gen.Target = golang.Target(target)
 		types := gen.GenerateTypeDefs()
 		switch target {
 		case "server":
@@ -115,6 +137,7 @@ func generateCode(lang, target, inputPath string, outputFile io.Writer) {
 		}
 	case "ts":
 		gen := ts.NewTsGenerator(apiSpec)
+		gen.Naming = naming
 		types := gen.GenerateTypeDefs()
 		switch target {
 		case "server":
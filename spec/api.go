@@ -2,25 +2,100 @@ package spec
 
 import (
 	"fmt"
+	"mime"
 	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Content types of request bodies.
+const (
+	JSONContentType      = "application/json"
+	FormContentType      = "application/x-www-form-urlencoded"
+	MultipartContentType = "multipart/form-data"
+)
+
+// BodyEncoding is how a request body is encoded for its content type.
+type BodyEncoding int
+
+const (
+	JSONEncoding BodyEncoding = iota
+	FormEncoding
+	MultipartEncoding
+	// RawEncoding bodies are strings or bytes sent as they are.
+	RawEncoding
 )
 
 type InputShape struct {
 	Params map[string]Field
 	Query  map[string]Field
-	Body   *TypeRef
+	// Headers holds the request headers, keyed by header name.
+	Headers map[string]Field
+	// Cookies holds the request cookies, keyed by cookie name.
+	Cookies map[string]Field
+	Body    *TypeRef
+	// ContentType is the media type the body is sent as, possibly with
+	// parameters. An empty content type stands for JSON.
+	ContentType string
+}
+
+// Encoding returns how the body is encoded. Media types with a +json suffix
+// are encoded as JSON.
+func (in *InputShape) Encoding() BodyEncoding {
+	if in.ContentType == "" {
+		return JSONEncoding
+	}
+	mediaType, _, _ := mime.ParseMediaType(in.ContentType)
+	switch {
+	case mediaType == JSONContentType || strings.HasSuffix(mediaType, "+json"):
+		return JSONEncoding
+	case mediaType == FormContentType:
+		return FormEncoding
+	case mediaType == MultipartContentType:
+		return MultipartEncoding
+	default:
+		return RawEncoding
+	}
+}
+
+// IsMultipart reports whether the body is sent as multipart/form-data.
+func (in *InputShape) IsMultipart() bool {
+	return in.Body != nil && in.Encoding() == MultipartEncoding
 }
 
 type Endpoint struct {
-	Name      string
-	Method    HTTPMethod
-	Path      *PathTemplate
-	Input     *InputShape
-	Responses []Response
+	// Source is the file the endpoint was declared in, if known.
+	Source      string
+	Doc         string
+	Annotations Annotations
+	Deprecated  *Deprecation
+	Name        string
+	Method      HTTPMethod
+	Path        *PathTemplate
+	Input       *InputShape
+	Responses   []Response
+}
+
+// Channel is a WebSocket connection on which the client sends messages of
+// type Client and the server messages of type Server, each encoded as JSON.
+type Channel struct {
+	// Source is the file the channel was declared in, if known.
+	Source      string
+	Doc         string
+	Annotations Annotations
+	Deprecated  *Deprecation
+	Name        string
+	Path        *PathTemplate
+	Client      TypeRef
+	Server      TypeRef
 }
 
 type APISpec struct {
+	Doc       string
 	Endpoints []Endpoint
+	Channels  []Channel
 	Types     map[string]*Type
 	Name      string
 	BaseURL   string
@@ -34,6 +109,23 @@ func (s *APISpec) ResolveTypeRef(typeRef TypeRef) (*Type, bool) {
 	return t, true
 }
 
+// ResolveUnderlyingType resolves typeRef, following aliases to the type
+// they ultimately name.
+func (s *APISpec) ResolveUnderlyingType(typeRef TypeRef) (*Type, bool) {
+	seen := make(map[string]bool)
+	for {
+		t, ok := s.ResolveTypeRef(typeRef)
+		if !ok || t.Kind != Alias {
+			return t, ok
+		}
+		if seen[typeRef.Name] {
+			return nil, false
+		}
+		seen[typeRef.Name] = true
+		typeRef = t.AliasType.Target
+	}
+}
+
 func (s *APISpec) ResolveTypeRefOrPanic(typeRef TypeRef) *Type {
 	t, ok := s.ResolveTypeRef(typeRef)
 	if !ok {
@@ -42,87 +134,552 @@ func (s *APISpec) ResolveTypeRefOrPanic(typeRef TypeRef) *Type {
 	return t
 }
 
+// refersToFile reports whether ref is a file, possibly within an array or
+// as a type argument.
+func (api *APISpec) refersToFile(ref TypeRef) bool {
+	if ref.IsArray() {
+		return api.refersToFile(*ref.Elem)
+	}
+	for _, arg := range ref.Args {
+		if api.refersToFile(arg) {
+			return true
+		}
+	}
+	typ, ok := api.ResolveUnderlyingType(ref)
+	return ok && typ.Kind == Primitive && typ.PrimitiveType == File
+}
+
+// carriesFile reports whether ref is a file or an object with file fields.
+func (api *APISpec) carriesFile(ref TypeRef) bool {
+	if api.refersToFile(ref) {
+		return true
+	}
+	typ, ok := api.ResolveUnderlyingType(ref.Base())
+	if !ok || typ.Kind != Object {
+		return false
+	}
+	for _, field := range typ.ObjectType.Fields {
+		if api.refersToFile(field.Ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMultipartBody reports whether the type called name is the body of a
+// multipart request.
+func (api *APISpec) isMultipartBody(name string) bool {
+	for _, endpoint := range api.Endpoints {
+		if endpoint.Input != nil && endpoint.Input.IsMultipart() && endpoint.Input.Body.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func validateMapKey(field Field, api *APISpec) error {
+	if field.Key == nil {
+		return fmt.Errorf("map field has no key type")
+	}
+	typ, ok := api.ResolveUnderlyingType(*field.Key)
+	if !ok {
+		return fmt.Errorf("unresolved type reference: %s", field.Key.Name)
+	}
+	if typ.Kind != Primitive || !slices.Contains([]PrimitiveType{String, Integer, Int64, UUID}, typ.PrimitiveType) {
+		return fmt.Errorf("map key type %s must be string, integer, int64 or uuid", field.Key.Name)
+	}
+	return nil
+}
+
+// validateConstraints checks that each constraint of field fits the field's
+// type and has a well-formed value.
+func validateConstraints(field Field, api *APISpec) error {
+	var primType PrimitiveType
+	isPrimitive := false
+	if field.Cardinality == Single && !field.Ref.IsArray() && !field.Ref.TypeParam {
+		if typ, ok := api.ResolveUnderlyingType(field.Ref); ok && typ.Kind == Primitive {
+			primType, isPrimitive = typ.PrimitiveType, true
+		}
+	}
+	isText := isPrimitive && slices.Contains([]PrimitiveType{String, UUID, Email, URL}, primType)
+	seen := make(map[ConstraintKind]bool)
+	bounds := make(map[ConstraintKind]float64)
+	for _, constraint := range field.Constraints {
+		if seen[constraint.Kind] {
+			return fmt.Errorf("duplicate constraint @%s", constraint.Kind)
+		}
+		seen[constraint.Kind] = true
+		var fits bool
+		switch constraint.Kind {
+		case MinLength, MaxLength, Pattern:
+			fits = isText
+		case Min, Max:
+			fits = isPrimitive && slices.Contains([]PrimitiveType{Integer, Int64, Float}, primType)
+		case MinItems, MaxItems:
+			fits = field.Cardinality == Multiple
+		default:
+			return fmt.Errorf("unknown constraint @%s", constraint.Kind)
+		}
+		if !fits {
+			return fmt.Errorf("constraint %s does not apply to type %s", constraint, field.Ref)
+		}
+		switch constraint.Kind {
+		case Pattern:
+			if _, err := regexp.Compile(constraint.Value); err != nil {
+				return fmt.Errorf("constraint %s has invalid pattern: %v", constraint, err)
+			}
+			continue
+		case MinLength, MaxLength, MinItems, MaxItems:
+			if n, err := strconv.Atoi(constraint.Value); err != nil || n < 0 {
+				return fmt.Errorf("constraint %s expects a non-negative integer", constraint)
+			}
+		case Min, Max:
+			if primType != Float {
+				if _, err := strconv.ParseInt(constraint.Value, 10, 64); err != nil {
+					return fmt.Errorf("constraint %s expects an integer", constraint)
+				}
+			}
+		}
+		value, err := strconv.ParseFloat(constraint.Value, 64)
+		if err != nil {
+			return fmt.Errorf("constraint %s expects a number", constraint)
+		}
+		bounds[constraint.Kind] = value
+	}
+	for lower, upper := range map[ConstraintKind]ConstraintKind{MinLength: MaxLength, Min: Max, MinItems: MaxItems} {
+		lo, hasLo := bounds[lower]
+		hi, hasHi := bounds[upper]
+		if hasLo && hasHi && lo > hi {
+			return fmt.Errorf("constraint @%s exceeds @%s", lower, upper)
+		}
+	}
+	return nil
+}
+
+// validateDefault checks that the default value of field, if any, is a
+// value of the field's type.
+func validateDefault(field Field, api *APISpec) error {
+	if field.Default == nil {
+		return nil
+	}
+	if !field.Optional {
+		return fmt.Errorf("default value %s requires an optional field", field.Default)
+	}
+	if field.Cardinality != Single || field.Ref.IsArray() || field.Ref.TypeParam {
+		return fmt.Errorf("default value %s does not apply to type %s", field.Default, field.Ref)
+	}
+	typ, ok := api.ResolveUnderlyingType(field.Ref)
+	if !ok {
+		return fmt.Errorf("unresolved type reference: %s", field.Ref)
+	}
+	value := field.Default
+	var fits bool
+	switch typ.Kind {
+	case Enum:
+		fits = value.Kind == SymbolValue && slices.Contains(typ.EnumType.Members, value.Literal)
+	case Primitive:
+		switch typ.PrimitiveType {
+		case Integer, Int64:
+			_, err := strconv.ParseInt(value.Literal, 10, 64)
+			fits = value.Kind == NumberValue && err == nil
+		case Float:
+			fits = value.Kind == NumberValue
+		case Boolean:
+			fits = value.Kind == SymbolValue && (value.Literal == "true" || value.Literal == "false")
+		case String, UUID, Email, URL, Decimal:
+			fits = value.Kind == StringValue
+		}
+	}
+	if !fits {
+		return fmt.Errorf("default value %s is not a valid %s", value, field.Ref)
+	}
+	return nil
+}
+
+// validateTypeRef checks that ref and its type arguments resolve, that type
+// parameters are in scope and that generic types get the right number of
+// type arguments.
+func (api *APISpec) validateTypeRef(ref TypeRef, typeParams []string) error {
+	if ref.IsArray() {
+		return api.validateTypeRef(*ref.Elem, typeParams)
+	}
+	if ref.TypeParam {
+		if !slices.Contains(typeParams, ref.Name) {
+			return fmt.Errorf("undeclared type parameter: %s", ref.Name)
+		}
+		return nil
+	}
+	typ, ok := api.ResolveTypeRef(ref)
+	if !ok {
+		return fmt.Errorf("unresolved type reference: %s", ref)
+	}
+	arity := 0
+	if typ.Kind == Object {
+		arity = len(typ.ObjectType.TypeParams)
+	}
+	if len(ref.Args) != arity {
+		return fmt.Errorf("type %s expects %d type arguments, got %d", ref.Name, arity, len(ref.Args))
+	}
+	for _, arg := range ref.Args {
+		if err := api.validateTypeRef(arg, typeParams); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateDerivation(derivation *Derivation, api *APISpec) error {
+	if err := api.validateTypeRef(derivation.Base, nil); err != nil {
+		return fmt.Errorf("%v in %s", err, derivation)
+	}
+	base := api.ResolveTypeRefOrPanic(derivation.Base)
+	if derivation.Base.IsArray() || base.Kind != Object {
+		return fmt.Errorf("%s must be applied to an object type", derivation)
+	}
+	for _, fieldName := range derivation.Fields {
+		if _, ok := base.ObjectType.Fields[fieldName]; !ok {
+			return fmt.Errorf("%s refers to unknown field %s", derivation, fieldName)
+		}
+	}
+	return nil
+}
+
+// extendsItself reports whether the object type called name is among its own
+// ancestors.
+func (api *APISpec) extendsItself(name string) bool {
+	seen := make(map[string]bool)
+	var extends func(typeName string) bool
+	extends = func(typeName string) bool {
+		typ, ok := api.Types[typeName]
+		if !ok || typ.Kind != Object || seen[typeName] {
+			return false
+		}
+		seen[typeName] = true
+		for _, parent := range typ.ObjectType.Parents {
+			if parent.Name == name || extends(parent.Name) {
+				return true
+			}
+		}
+		return false
+	}
+	return extends(name)
+}
+
 func validateObject(obj *ObjectType, api *APISpec) error {
+	if obj.Derivation != nil {
+		if err := validateDerivation(obj.Derivation, api); err != nil {
+			return err
+		}
+	}
+	for _, parent := range obj.Parents {
+		if parent.TypeParam || parent.IsArray() {
+			return fmt.Errorf("parent type %s must be an object type", parent)
+		}
+		if err := api.validateTypeRef(parent, obj.TypeParams); err != nil {
+			return fmt.Errorf("%v in parent type", err)
+		}
+		typ := api.ResolveTypeRefOrPanic(parent)
+		if typ.Kind != Object {
+			return fmt.Errorf("parent type %s must be an object type", parent)
+		}
+		// The fields of an object include those it inherits, which must
+		// keep their parents' types.
+		for fieldName, parentField := range typ.ObjectType.Fields {
+			parentField.Ref = parentField.Ref.Substitute(typ.ObjectType.TypeParams, parent.Args)
+			if field, ok := obj.Fields[fieldName]; ok && !field.SameType(parentField) {
+				return fmt.Errorf("field %s conflicts with the field inherited from %s", fieldName, parent)
+			}
+		}
+	}
 	for fieldName, field := range obj.Fields {
-		typ, ok := api.ResolveTypeRef(field.Ref)
+		if field.Cardinality == Map {
+			if err := validateMapKey(field, api); err != nil {
+				return fmt.Errorf("%v in field %s", err, fieldName)
+			}
+		}
+		if err := api.validateTypeRef(field.Ref, obj.TypeParams); err != nil {
+			return fmt.Errorf("%v in field %s", err, fieldName)
+		}
+		if err := validateConstraints(field, api); err != nil {
+			return fmt.Errorf("%v in field %s", err, fieldName)
+		}
+		if err := validateDefault(field, api); err != nil {
+			return fmt.Errorf("%v in field %s", err, fieldName)
+		}
+	}
+	return nil
+}
+
+func validateEnum(typeName string, enum *EnumType) error {
+	if len(enum.Members) == 0 {
+		return fmt.Errorf("enum %s has no members", typeName)
+	}
+	seen := make(map[string]bool)
+	for _, member := range enum.Members {
+		if seen[member] {
+			return fmt.Errorf("enum %s has duplicate member: %s", typeName, member)
+		}
+		seen[member] = true
+	}
+	return nil
+}
+
+func validateAlias(typeName string, alias *AliasType, api *APISpec) error {
+	if _, ok := api.ResolveTypeRef(alias.Target); !ok {
+		return fmt.Errorf("unresolved type reference: %s in alias %s", alias.Target.Name, typeName)
+	}
+	typ, ok := api.ResolveUnderlyingType(alias.Target)
+	if !ok {
+		return fmt.Errorf("alias %s is cyclic", typeName)
+	}
+	if typ.Kind != Primitive {
+		return fmt.Errorf("alias %s must name a primitive type, got %s", typeName, typ.Kind)
+	}
+	return nil
+}
+
+func validateUnion(typeName string, union *UnionType, api *APISpec) error {
+	if len(union.Variants) == 0 {
+		return fmt.Errorf("union %s has no variants", typeName)
+	}
+	seen := make(map[string]bool)
+	tags := make(map[string]string)
+	for _, variant := range union.Variants {
+		if seen[variant.Name] {
+			return fmt.Errorf("union %s has duplicate variant: %s", typeName, variant.Name)
+		}
+		seen[variant.Name] = true
+		if other, ok := tags[VariantTag(variant)]; ok {
+			return fmt.Errorf("union %s variants %s and %s share the tag %s", typeName, other, variant.Name, VariantTag(variant))
+		}
+		tags[VariantTag(variant)] = variant.Name
+
+		if err := api.validateTypeRef(variant, nil); err != nil {
+			return fmt.Errorf("%v in union %s", err, typeName)
+		}
+		typ := api.ResolveTypeRefOrPanic(variant)
+		if typ.Kind != Object {
+			return fmt.Errorf("union %s variant %s must be an object type", typeName, variant.Name)
+		}
+		field, ok := typ.ObjectType.Fields[union.Discriminator]
 		if !ok {
-			return fmt.Errorf("unresolved type reference: %s in field %s", field.Ref.Name, fieldName)
+			return fmt.Errorf("union %s variant %s is missing discriminator field %s", typeName, variant.Name, union.Discriminator)
 		}
-		switch typ.Kind {
-		case Object:
-			if err := validateObject(typ.ObjectType, api); err != nil {
-				return err
-			}
-		case Primitive:
-			// Primitive types are always valid
-		default:
-			return fmt.Errorf("unknown type kind: %s in field %s", typ.Kind, fieldName)
+		if field.Optional || field.Nullable || field.Cardinality != Single {
+			return fmt.Errorf("union %s variant %s discriminator field %s must be required and single-valued", typeName, variant.Name, union.Discriminator)
+		}
+		if field.Ref.Name != "string" {
+			return fmt.Errorf("union %s variant %s discriminator field %s must be a string", typeName, variant.Name, union.Discriminator)
 		}
 	}
 	return nil
 }
 
 func (api *APISpec) ValidateTypes() error {
+	if err := api.CheckNaming(Naming{}); err != nil {
+		return err
+	}
 	for typeName, typ := range api.Types {
-		switch typ.Kind {
-		case Object:
-			if err := validateObject(typ.ObjectType, api); err != nil {
-				return err
+		if err := api.validateType(typeName, typ); err != nil {
+			return inSource(typ.Source, err)
+		}
+	}
+	return nil
+}
+
+func (api *APISpec) validateType(typeName string, typ *Type) error {
+	switch typ.Kind {
+	case Object:
+		if api.extendsItself(typeName) {
+			return fmt.Errorf("object %s extends itself", typeName)
+		}
+		if err := validateObject(typ.ObjectType, api); err != nil {
+			return err
+		}
+		if !api.isMultipartBody(typeName) {
+			for fieldName, field := range typ.ObjectType.Fields {
+				if api.refersToFile(field.Ref) {
+					return fmt.Errorf("object %s field %s is a file, which is only allowed in multipart bodies", typeName, fieldName)
+				}
 			}
-		case Primitive:
-			// Primitive types are always valid
-		default:
-			return fmt.Errorf("unknown type kind: %s for type %s", typ.Kind, typeName)
 		}
+	case Enum:
+		if err := validateEnum(typeName, typ.EnumType); err != nil {
+			return err
+		}
+	case Union:
+		if err := validateUnion(typeName, typ.UnionType, api); err != nil {
+			return err
+		}
+	case Alias:
+		if err := validateAlias(typeName, typ.AliasType, api); err != nil {
+			return err
+		}
+	case Primitive:
+		// Primitive types are always valid
+	default:
+		return fmt.Errorf("unknown type kind: %s for type %s", typ.Kind, typeName)
 	}
 	return nil
 }
 
 func (api *APISpec) ValidateEndpoints() error {
 	for _, endpoint := range api.Endpoints {
-		if endpoint.Input != nil {
-			for paramName, field := range endpoint.Input.Params {
-				if field.Optional {
-					return fmt.Errorf("endpoint %s param %s cannot be optional", endpoint.Name, paramName)
-				}
-				if field.Nullable {
-					return fmt.Errorf("endpoint %s param %s cannot be nullable", endpoint.Name, paramName)
-				}
-				if _, ok := api.ResolveTypeRef(field.Ref); !ok {
-					return fmt.Errorf("unresolved type reference: %s in endpoint %s param %s", field.Ref.Name, endpoint.Name, paramName)
-				}
+		if err := api.validateEndpoint(endpoint); err != nil {
+			return inSource(endpoint.Source, err)
+		}
+	}
+	return nil
+}
+
+func (api *APISpec) validateEndpoint(endpoint Endpoint) error {
+	if endpoint.Input != nil {
+		for paramName, field := range endpoint.Input.Params {
+			if field.Optional {
+				return fmt.Errorf("endpoint %s param %s cannot be optional", endpoint.Name, paramName)
 			}
-			for queryName, field := range endpoint.Input.Query {
-				if _, ok := api.ResolveTypeRef(field.Ref); !ok {
-					return fmt.Errorf("unresolved type reference: %s in endpoint %s query %s", field.Ref.Name, endpoint.Name, queryName)
-				}
+			// Params are always present in the path, so a default never applies.
+			if field.Default != nil {
+				return fmt.Errorf("endpoint %s param %s cannot have a default", endpoint.Name, paramName)
 			}
-			if endpoint.Input.Body != nil {
-				if _, ok := api.ResolveTypeRef(*endpoint.Input.Body); !ok {
-					return fmt.Errorf("unresolved type reference: %s in endpoint %s body", endpoint.Input.Body.Name, endpoint.Name)
-				}
+			if field.Nullable {
+				return fmt.Errorf("endpoint %s param %s cannot be nullable", endpoint.Name, paramName)
+			}
+			if field.Cardinality == Map {
+				return fmt.Errorf("endpoint %s param %s cannot be a map", endpoint.Name, paramName)
+			}
+			if field.Ref.IsArray() {
+				return fmt.Errorf("endpoint %s param %s cannot be a nested array", endpoint.Name, paramName)
+			}
+			if err := api.validateTypeRef(field.Ref, nil); err != nil {
+				return fmt.Errorf("%v in endpoint %s param %s", err, endpoint.Name, paramName)
+			}
+			if api.refersToFile(field.Ref) {
+				return fmt.Errorf("endpoint %s param %s cannot be a file", endpoint.Name, paramName)
+			}
+			if err := validateConstraints(field, api); err != nil {
+				return fmt.Errorf("%v in endpoint %s param %s", err, endpoint.Name, paramName)
+			}
+			if err := validateDefault(field, api); err != nil {
+				return fmt.Errorf("%v in endpoint %s param %s", err, endpoint.Name, paramName)
+			}
+		}
+		for queryName, field := range endpoint.Input.Query {
+			if field.Cardinality == Map {
+				return fmt.Errorf("endpoint %s query %s cannot be a map", endpoint.Name, queryName)
+			}
+			if field.Ref.IsArray() {
+				return fmt.Errorf("endpoint %s query %s cannot be a nested array", endpoint.Name, queryName)
+			}
+			if err := api.validateTypeRef(field.Ref, nil); err != nil {
+				return fmt.Errorf("%v in endpoint %s query %s", err, endpoint.Name, queryName)
+			}
+			if api.refersToFile(field.Ref) {
+				return fmt.Errorf("endpoint %s query %s cannot be a file", endpoint.Name, queryName)
+			}
+			if err := validateConstraints(field, api); err != nil {
+				return fmt.Errorf("%v in endpoint %s query %s", err, endpoint.Name, queryName)
+			}
+			if err := validateDefault(field, api); err != nil {
+				return fmt.Errorf("%v in endpoint %s query %s", err, endpoint.Name, queryName)
+			}
+		}
+		if err := api.validateHeaders(endpoint.Input.Headers, "endpoint "+endpoint.Name); err != nil {
+			return err
+		}
+		if err := api.validateCookies(endpoint.Input.Cookies, "endpoint "+endpoint.Name); err != nil {
+			return err
+		}
+		if endpoint.Input.Body != nil {
+			if err := api.validateTypeRef(*endpoint.Input.Body, nil); err != nil {
+				return fmt.Errorf("%v in endpoint %s body", err, endpoint.Name)
+			}
+			if err := api.validateBodyEncoding(endpoint); err != nil {
+				return err
 			}
 		}
+	}
+
+	if len(endpoint.Responses) == 0 {
+		return fmt.Errorf("endpoint %s has no responses defined", endpoint.Name)
+	}
 
-		if len(endpoint.Responses) == 0 {
-			return fmt.Errorf("endpoint %s has no responses defined", endpoint.Name)
+	duplicateCheck := make(map[int]bool)
+	successSeen := false
+	for _, resp := range endpoint.Responses {
+		if resp.Code < 100 || resp.Code > 599 {
+			return fmt.Errorf("endpoint %s has invalid response code: %d", endpoint.Name, resp.Code)
 		}
+		if _, exists := duplicateCheck[resp.Code]; exists {
+			return fmt.Errorf("endpoint %s has duplicate response code: %d", endpoint.Name, resp.Code)
+		}
+		duplicateCheck[resp.Code] = true
 
-		duplicateCheck := make(map[int]bool)
-		for _, resp := range endpoint.Responses {
-			if resp.Code < 100 || resp.Code > 599 {
-				return fmt.Errorf("endpoint %s has invalid response code: %d", endpoint.Name, resp.Code)
+		// Generated clients decode every other response as a JSON error.
+		isSuccess := !successSeen && resp.Code >= 200 && resp.Code < 300
+		successSeen = successSeen || isSuccess
+		if resp.IsStream() {
+			if !isSuccess {
+				return fmt.Errorf("endpoint %s response %d cannot be streamed, only the success response can", endpoint.Name, resp.Code)
 			}
-			if _, exists := duplicateCheck[resp.Code]; exists {
-				return fmt.Errorf("endpoint %s has duplicate response code: %d", endpoint.Name, resp.Code)
+			if resp.Stream != SSE && resp.Stream != NDJSON {
+				return fmt.Errorf("endpoint %s response %d has unknown stream format %s", endpoint.Name, resp.Code, resp.Stream)
 			}
-			duplicateCheck[resp.Code] = true
-
-			if resp.Ref != nil {
-				if _, ok := api.ResolveTypeRef(*resp.Ref); !ok {
-					return fmt.Errorf("unresolved type reference: %s in endpoint %s response %d", resp.Ref.Name, endpoint.Name, resp.Code)
-				}
+			if len(resp.Headers) > 0 {
+				return fmt.Errorf("endpoint %s response %d is streamed and cannot declare headers", endpoint.Name, resp.Code)
+			}
+		}
+		if resp.IsBinary() {
+			if !isSuccess {
+				return fmt.Errorf("endpoint %s response %d cannot be binary, only the success response can", endpoint.Name, resp.Code)
 			}
+			if _, _, err := mime.ParseMediaType(resp.ContentType); err != nil {
+				return fmt.Errorf("endpoint %s response %d has invalid content type %q: %v", endpoint.Name, resp.Code, resp.ContentType, err)
+			}
+		}
+		if resp.Ref != nil {
+			if err := api.validateTypeRef(*resp.Ref, nil); err != nil {
+				return fmt.Errorf("%v in endpoint %s response %d", err, endpoint.Name, resp.Code)
+			}
+			if api.carriesFile(*resp.Ref) {
+				return fmt.Errorf("endpoint %s response %d cannot contain files", endpoint.Name, resp.Code)
+			}
+		}
+		scope := fmt.Sprintf("endpoint %s response %d", endpoint.Name, resp.Code)
+		if err := api.validateHeaders(resp.Headers, scope); err != nil {
+			return err
+		}
+		for headerName, field := range resp.Headers {
+			if field.Default != nil {
+				return fmt.Errorf("%s header %s cannot have a default value", scope, headerName)
+			}
+		}
+	}
+	return nil
+}
+
+func (api *APISpec) ValidateChannels() error {
+	for _, channel := range api.Channels {
+		if err := api.validateChannel(channel); err != nil {
+			return inSource(channel.Source, err)
+		}
+	}
+	return nil
+}
+
+func (api *APISpec) validateChannel(channel Channel) error {
+	if channel.Path == nil || channel.Path.template == "" {
+		return fmt.Errorf("channel %s has invalid path template", channel.Name)
+	}
+	if len(channel.Path.Params()) > 0 {
+		return fmt.Errorf("channel %s path cannot have parameters", channel.Name)
+	}
+	for side, ref := range map[string]TypeRef{"client": channel.Client, "server": channel.Server} {
+		if err := api.validateTypeRef(ref, nil); err != nil {
+			return fmt.Errorf("%v in channel %s %s messages", err, channel.Name, side)
+		}
+		if api.carriesFile(ref) {
+			return fmt.Errorf("channel %s %s messages cannot contain files", channel.Name, side)
 		}
 	}
 	return nil
@@ -130,28 +687,145 @@ func (api *APISpec) ValidateEndpoints() error {
 
 func (api *APISpec) ValidatePaths() error {
 	for _, endpoint := range api.Endpoints {
-		if endpoint.Path == nil || endpoint.Path.template == "" {
-			return fmt.Errorf("endpoint %s has invalid path template", endpoint.Name)
+		if err := api.validatePath(endpoint); err != nil {
+			return inSource(endpoint.Source, err)
 		}
+	}
+	return nil
+}
 
-		if endpoint.Input != nil && len(endpoint.Path.Params()) > 0 {
-			if len(endpoint.Path.Params()) != len(endpoint.Input.Params) {
-				return fmt.Errorf("endpoint %s path parameters do not match input parameters", endpoint.Name)
-			}
-			for _, param := range endpoint.Path.Params() {
-				if _, ok := endpoint.Input.Params[param]; !ok {
-					return fmt.Errorf("endpoint %s path parameter %s not defined in input parameters", endpoint.Name, param)
-				}
+func (api *APISpec) validatePath(endpoint Endpoint) error {
+	if endpoint.Path == nil || endpoint.Path.template == "" {
+		return fmt.Errorf("endpoint %s has invalid path template", endpoint.Name)
+	}
+
+	if endpoint.Input != nil && len(endpoint.Path.Params()) > 0 {
+		if len(endpoint.Path.Params()) != len(endpoint.Input.Params) {
+			return fmt.Errorf("endpoint %s path parameters do not match input parameters", endpoint.Name)
+		}
+		for _, param := range endpoint.Path.Params() {
+			if _, ok := endpoint.Input.Params[param]; !ok {
+				return fmt.Errorf("endpoint %s path parameter %s not defined in input parameters", endpoint.Name, param)
 			}
 		}
+	}
+
+	if len(endpoint.Path.params) > 0 && (endpoint.Input == nil || len(endpoint.Input.Params) == 0) {
+		return fmt.Errorf("endpoint %s specifies path parameters not defined in template", endpoint.Name)
+	}
+	return nil
+}
+
+var headerNamePattern = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// validateBodyEncoding checks that the body of endpoint can be encoded as
+// its content type declares.
+func (api *APISpec) validateBodyEncoding(endpoint Endpoint) error {
+	input := endpoint.Input
+	if input.ContentType != "" {
+		if _, _, err := mime.ParseMediaType(input.ContentType); err != nil {
+			return fmt.Errorf("endpoint %s body has invalid content type %q: %v", endpoint.Name, input.ContentType, err)
+		}
+	}
+	if input.Encoding() != MultipartEncoding && api.carriesFile(*input.Body) {
+		return fmt.Errorf("endpoint %s body contains files, which are only allowed in multipart bodies", endpoint.Name)
+	}
+	switch input.Encoding() {
+	case FormEncoding:
+		return api.validateFlatBody(endpoint, "form")
+	case MultipartEncoding:
+		return api.validateFlatBody(endpoint, "multipart")
+	case RawEncoding:
+		typ, ok := api.ResolveUnderlyingType(*input.Body)
+		if input.Body.IsArray() || !ok || typ.Kind != Primitive || (typ.PrimitiveType != String && typ.PrimitiveType != Bytes) {
+			return fmt.Errorf("endpoint %s body of content type %s must be a string or bytes, got %s", endpoint.Name, input.ContentType, input.Body)
+		}
+	}
+	return nil
+}
+
+// validateFlatBody checks that a form or multipart body is an object whose
+// fields are primitives or enums, or lists of them, since form values
+// cannot nest.
+func (api *APISpec) validateFlatBody(endpoint Endpoint, encoding string) error {
+	body := *endpoint.Input.Body
+	typ, ok := api.ResolveUnderlyingType(body)
+	if body.IsArray() || !ok || typ.Kind != Object {
+		return fmt.Errorf("endpoint %s %s body must be an object type", endpoint.Name, encoding)
+	}
+	if len(body.Args) > 0 {
+		return fmt.Errorf("endpoint %s %s body cannot be generic", endpoint.Name, encoding)
+	}
+	for fieldName, field := range typ.ObjectType.Fields {
+		if field.Nullable {
+			return fmt.Errorf("endpoint %s %s body field %s cannot be nullable", endpoint.Name, encoding, fieldName)
+		}
+		if field.Cardinality == Map || field.Ref.IsArray() {
+			return fmt.Errorf("endpoint %s %s body field %s must be a single value or a list", endpoint.Name, encoding, fieldName)
+		}
+		if fieldType, ok := api.ResolveUnderlyingType(field.Ref); !ok || (fieldType.Kind != Primitive && fieldType.Kind != Enum) {
+			return fmt.Errorf("endpoint %s %s body field %s must be a primitive or enum type, got %s", endpoint.Name, encoding, fieldName, field.Ref)
+		}
+	}
+	return nil
+}
+
+// validateHeaders checks that the headers of a request or response, named
+// by scope, are single primitive values under valid, distinct names.
+func (api *APISpec) validateHeaders(headers map[string]Field, scope string) error {
+	return api.validateParameters(headers, scope, "header", true)
+}
+
+// validateCookies checks the request cookies of an endpoint like headers.
+// Cookie names are case-sensitive.
+func (api *APISpec) validateCookies(cookies map[string]Field, scope string) error {
+	return api.validateParameters(cookies, scope, "cookie", false)
+}
 
-		if len(endpoint.Path.params) > 0 && (endpoint.Input == nil || len(endpoint.Input.Params) == 0) {
-			return fmt.Errorf("endpoint %s specifies path parameters not defined in template", endpoint.Name)
+func (api *APISpec) validateParameters(fields map[string]Field, scope, kind string, foldCase bool) error {
+	seen := make(map[string]string)
+	for name, field := range fields {
+		if !headerNamePattern.MatchString(name) {
+			return fmt.Errorf("%s %s %q is not a valid %s name", scope, kind, name, kind)
+		}
+		if foldCase {
+			if other, ok := seen[strings.ToLower(name)]; ok {
+				return fmt.Errorf("%s %ss %s and %s differ only in case", scope, kind, other, name)
+			}
+			seen[strings.ToLower(name)] = name
+		}
+		if field.Nullable {
+			return fmt.Errorf("%s %s %s cannot be nullable", scope, kind, name)
+		}
+		if field.Cardinality != Single || field.Ref.IsArray() {
+			return fmt.Errorf("%s %s %s must be single-valued", scope, kind, name)
+		}
+		if err := api.validateTypeRef(field.Ref, nil); err != nil {
+			return fmt.Errorf("%v in %s %s %s", err, scope, kind, name)
+		}
+		if typ, _ := api.ResolveUnderlyingType(field.Ref); typ == nil || typ.Kind != Primitive {
+			return fmt.Errorf("%s %s %s must be a primitive type, got %s", scope, kind, name, field.Ref)
+		} else if typ.PrimitiveType == File {
+			return fmt.Errorf("%s %s %s cannot be a file", scope, kind, name)
+		}
+		if err := validateConstraints(field, api); err != nil {
+			return fmt.Errorf("%v in %s %s %s", err, scope, kind, name)
+		}
+		if err := validateDefault(field, api); err != nil {
+			return fmt.Errorf("%v in %s %s %s", err, scope, kind, name)
 		}
 	}
 	return nil
 }
 
+// inSource prefixes err with the file it arose in, if known.
+func inSource(source string, err error) error {
+	if source == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %v", source, err)
+}
+
 func (api *APISpec) Validate() error {
 	if api.Name == "" {
 		return fmt.Errorf("API name cannot be empty")
@@ -174,6 +848,9 @@ func (api *APISpec) Validate() error {
 	if err := api.ValidatePaths(); err != nil {
 		return err
 	}
+	if err := api.ValidateChannels(); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -185,6 +862,15 @@ func (api *APISpec) Responses() []Response {
 	return responses
 }
 
+// kindOf describes the kind of the type ref refers to, or ref itself when it
+// does not name a declared type, as with type parameters.
+func (api *APISpec) kindOf(ref TypeRef) string {
+	if typ, ok := api.Types[ref.Base().Name]; ok && !ref.TypeParam {
+		return string(typ.Kind)
+	}
+	return ref.String()
+}
+
 func (api *APISpec) String() string {
 	f := NewFormatter()
 
@@ -205,8 +891,7 @@ func (api *APISpec) String() string {
 				f.Line("Params:")
 				f.Indent()
 				for paramName, field := range endpoint.Input.Params {
-					typ := api.Types[field.Ref.Name]
-					f.Line("- %s: %s", paramName, typ.Kind)
+					f.Line("- %s: %s", paramName, api.kindOf(field.Ref))
 				}
 				f.Dedent()
 			}
@@ -214,28 +899,64 @@ func (api *APISpec) String() string {
 				f.Line("Query:")
 				f.Indent()
 				for queryName, field := range endpoint.Input.Query {
-					typ := api.Types[field.Ref.Name]
-					f.Line("- %s: %s", queryName, typ.Kind)
+					if field.Default != nil {
+						f.Line("- %s: %s (default %s)", queryName, api.kindOf(field.Ref), field.Default)
+						continue
+					}
+					f.Line("- %s: %s", queryName, api.kindOf(field.Ref))
+				}
+				f.Dedent()
+			}
+			if len(endpoint.Input.Headers) > 0 {
+				f.Line("Headers:")
+				f.Indent()
+				for headerName, field := range endpoint.Input.Headers {
+					if field.Default != nil {
+						f.Line("- %s: %s (default %s)", headerName, api.kindOf(field.Ref), field.Default)
+						continue
+					}
+					f.Line("- %s: %s", headerName, api.kindOf(field.Ref))
+				}
+				f.Dedent()
+			}
+			if len(endpoint.Input.Cookies) > 0 {
+				f.Line("Cookies:")
+				f.Indent()
+				for cookieName, field := range endpoint.Input.Cookies {
+					if field.Default != nil {
+						f.Line("- %s: %s (default %s)", cookieName, api.kindOf(field.Ref), field.Default)
+						continue
+					}
+					f.Line("- %s: %s", cookieName, api.kindOf(field.Ref))
 				}
 				f.Dedent()
 			}
 			if endpoint.Input.Body != nil {
-				f.Line("Body:")
+				if endpoint.Input.Encoding() != JSONEncoding {
+					f.Line("Body (%s):", endpoint.Input.ContentType)
+				} else {
+					f.Line("Body:")
+				}
 				f.Indent()
-				typ := api.Types[endpoint.Input.Body.Name]
+				typ := api.Types[endpoint.Input.Body.Base().Name]
 				switch typ.Kind {
 				case Object:
 					for fieldName, field := range typ.ObjectType.Fields {
-						fieldType := api.Types[field.Ref.Name]
 						optionalStr := ""
 						if field.Optional {
 							optionalStr = " (optional)"
 						}
+						if field.Default != nil {
+							optionalStr += fmt.Sprintf(" (default %s)", field.Default)
+						}
 						cardStr := ""
-						if field.Cardinality == Multiple {
+						switch field.Cardinality {
+						case Multiple:
 							cardStr = "[]"
+						case Map:
+							cardStr = fmt.Sprintf("map[%s]", field.Key.Name)
 						}
-						f.Line("- %s: %s%s%s", fieldName, cardStr, fieldType.Kind, optionalStr)
+						f.Line("- %s: %s%s%s", fieldName, cardStr, api.kindOf(field.Ref), optionalStr)
 					}
 				default:
 					f.Line("- %s", typ.Kind)
@@ -247,12 +968,36 @@ func (api *APISpec) String() string {
 		f.Line("Responses:")
 		f.Indent()
 		for _, resp := range endpoint.Responses {
-			if resp.Ref != nil {
-				typ := api.Types[resp.Ref.Name]
-				f.Line("- %d: %s", resp.Code, typ.Kind)
+			if resp.IsBinary() {
+				f.Line("- %d: binary (%s)", resp.Code, resp.ContentType)
+			} else if resp.IsStream() {
+				f.Line("- %d: stream of %s (%s)", resp.Code, api.kindOf(*resp.Ref), resp.Stream.ContentType())
+			} else if resp.Ref != nil {
+				f.Line("- %d: %s", resp.Code, api.kindOf(*resp.Ref))
 			} else {
 				f.Line("- %d: no body", resp.Code)
 			}
+			f.Indent()
+			for headerName, field := range resp.Headers {
+				f.Line("- header %s: %s", headerName, api.kindOf(field.Ref))
+			}
+			f.Dedent()
+		}
+		f.Dedent()
+		f.Dedent()
+	}
+	f.Dedent()
+
+	if len(api.Channels) > 0 {
+		f.Line("Channels:")
+		f.Indent()
+		for _, channel := range api.Channels {
+			f.Line("Channel: %s", channel.Name)
+			f.Indent()
+			f.Line("Path: %s", channel.Path.String())
+			f.Line("Client: %s", channel.Client)
+			f.Line("Server: %s", channel.Server)
+			f.Dedent()
 		}
 		f.Dedent()
 	}
@@ -261,10 +1006,15 @@ func (api *APISpec) String() string {
 }
 
 func NewAPISpec(name string, baseURL string, endpoints []Endpoint, types map[string]*Type) (*APISpec, error) {
-	types["string"] = &Type{Kind: Primitive, PrimitiveType: String}
-	types["integer"] = &Type{Kind: Primitive, PrimitiveType: Integer}
-	types["float"] = &Type{Kind: Primitive, PrimitiveType: Float}
-	types["boolean"] = &Type{Kind: Primitive, PrimitiveType: Boolean}
+	return NewAPISpecWithChannels(name, baseURL, endpoints, nil, types)
+}
+
+// NewAPISpecWithChannels is like NewAPISpec for an API that also serves
+// WebSocket channels, which are validated along with the rest of the API.
+func NewAPISpecWithChannels(name string, baseURL string, endpoints []Endpoint, channels []Channel, types map[string]*Type) (*APISpec, error) {
+	for _, prim := range []PrimitiveType{String, Integer, Float, Boolean, DateTime, Date, UUID, Bytes, Int64, Decimal, Email, URL, File} {
+		types[prim.String()] = &Type{Kind: Primitive, PrimitiveType: prim}
+	}
 	normalizedUrl := baseURL
 	if len(normalizedUrl) > 0 && normalizedUrl[len(normalizedUrl)-1] != '/' {
 		normalizedUrl += "/"
@@ -274,6 +1024,7 @@ func NewAPISpec(name string, baseURL string, endpoints []Endpoint, types map[str
 		Name:      name,
 		BaseURL:   normalizedUrl,
 		Endpoints: endpoints,
+		Channels:  channels,
 		Types:     types,
 	}
 
@@ -1,6 +1,7 @@
 package spec_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/printchard/scapi/spec"
@@ -51,7 +52,7 @@ func DefaultApiSpec() *spec.APISpec {
 			ObjectType: &spec.ObjectType{
 				Fields: map[string]spec.Field{
 					"id":  {Ref: spec.TypeRef{Name: "string"}},
-					"age": {Ref: spec.TypeRef{Name: "number"}, Optional: true},
+					"age": {Ref: spec.TypeRef{Name: "integer"}, Optional: true},
 				},
 			},
 		},
@@ -60,9 +61,597 @@ func DefaultApiSpec() *spec.APISpec {
 	return api
 }
 
+// validateEndpoint validates an API holding only endpoint and returns the
+// error NewAPISpec reports, if any. Unless the endpoint sets them, it is a GET
+// of /test called Test responding with a User, an empty object type declared
+// along with types.
+func validateEndpoint(endpoint spec.Endpoint, types map[string]*spec.Type) error {
+	if endpoint.Name == "" {
+		endpoint.Name = "Test"
+	}
+	if endpoint.Path == nil {
+		endpoint.Path = spec.NewPathTemplate("/test")
+	}
+	if endpoint.Responses == nil {
+		endpoint.Responses = []spec.Response{{Code: 200, Ref: &spec.TypeRef{Name: "User"}}}
+	}
+	if types == nil {
+		types = map[string]*spec.Type{}
+	}
+	types["User"] = &spec.Type{Kind: spec.Object, ObjectType: &spec.ObjectType{Fields: map[string]spec.Field{}}}
+	_, err := spec.NewAPISpec("TestAPI", "http://localhost:1", []spec.Endpoint{endpoint}, types)
+	return err
+}
+
+// validateField validates an API with no endpoints whose types are a copy of
+// types along with an object type Holder, whose only field, value, is field.
+func validateField(field spec.Field, types map[string]*spec.Type) error {
+	all := map[string]*spec.Type{}
+	for name, typ := range types {
+		all[name] = typ
+	}
+	all["Holder"] = &spec.Type{Kind: spec.Object, ObjectType: &spec.ObjectType{Fields: map[string]spec.Field{"value": field}}}
+	_, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, all)
+	return err
+}
+
 func TestParseApiSpec(t *testing.T) {
 	api := DefaultApiSpec()
 	if err := api.Validate(); err != nil {
 		t.Fatalf("expected valid API spec, got error: %v", err)
 	}
 }
+
+func TestEnumDuplicateMembers(t *testing.T) {
+	types := map[string]*spec.Type{
+		"Status": {
+			Kind:     spec.Enum,
+			EnumType: &spec.EnumType{Members: []string{"ACTIVE", "PENDING", "ACTIVE"}},
+		},
+	}
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err == nil {
+		t.Fatal("expected error for duplicate enum member, got nil")
+	}
+}
+
+func TestUnionVariantMissingDiscriminator(t *testing.T) {
+	types := map[string]*spec.Type{
+		"EmailNotification": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				Fields: map[string]spec.Field{
+					"kind":    {Ref: spec.TypeRef{Name: "string"}},
+					"address": {Ref: spec.TypeRef{Name: "string"}},
+				},
+			},
+		},
+		"SmsNotification": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				Fields: map[string]spec.Field{
+					"phone": {Ref: spec.TypeRef{Name: "string"}},
+				},
+			},
+		},
+		"Notification": {
+			Kind: spec.Union,
+			UnionType: &spec.UnionType{
+				Variants: []spec.TypeRef{
+					{Name: "EmailNotification"},
+					{Name: "SmsNotification"},
+				},
+				Discriminator: "kind",
+			},
+		},
+	}
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err == nil {
+		t.Fatal("expected error for variant without discriminator field, got nil")
+	}
+}
+
+func TestObjectParents(t *testing.T) {
+	newObject := func(fields map[string]spec.Field, parents ...string) *spec.Type {
+		obj := &spec.ObjectType{Fields: fields}
+		for _, parent := range parents {
+			obj.Parents = append(obj.Parents, spec.TypeRef{Name: parent})
+		}
+		return &spec.Type{Kind: spec.Object, ObjectType: obj}
+	}
+	id := map[string]spec.Field{"id": {Ref: spec.TypeRef{Name: "string"}}}
+	tests := []struct {
+		name    string
+		types   map[string]*spec.Type
+		wantErr bool
+	}{
+		{"inheritance", map[string]*spec.Type{
+			"Entity": newObject(id),
+			"User":   newObject(id, "Entity"),
+		}, false},
+		{"diamond", map[string]*spec.Type{
+			"Entity": newObject(id),
+			"Named":  newObject(id, "Entity"),
+			"Priced": newObject(id, "Entity"),
+			"User":   newObject(id, "Named", "Priced"),
+		}, false},
+		{"conflicting redeclaration", map[string]*spec.Type{
+			"Entity": newObject(id),
+			"User":   newObject(map[string]spec.Field{"id": {Ref: spec.TypeRef{Name: "integer"}}}, "Entity"),
+		}, true},
+		{"parent cycle", map[string]*spec.Type{
+			"Entity": newObject(id, "User"),
+			"User":   newObject(id, "Entity"),
+		}, true},
+		{"non-object parent", map[string]*spec.Type{
+			"Role": {Kind: spec.Enum, EnumType: &spec.EnumType{Members: []string{"admin"}}},
+			"User": newObject(id, "Role"),
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, tt.types)
+			if tt.wantErr && err == nil {
+				t.Errorf("expected error, got nil")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("expected valid API spec, got error: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnionVariantTags(t *testing.T) {
+	if tag := spec.VariantTag(spec.TypeRef{Name: "billing.Card"}); tag != "Card" {
+		t.Errorf("expected billing.Card to be tagged Card, got %s", tag)
+	}
+
+	newVariant := func() *spec.Type {
+		return &spec.Type{
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				Fields: map[string]spec.Field{"kind": {Ref: spec.TypeRef{Name: "string"}}},
+			},
+		}
+	}
+	types := map[string]*spec.Type{
+		"billing.Card":  newVariant(),
+		"shipping.Card": newVariant(),
+		"Payment": {
+			Kind: spec.Union,
+			UnionType: &spec.UnionType{
+				Variants:      []spec.TypeRef{{Name: "billing.Card"}, {Name: "shipping.Card"}},
+				Discriminator: "kind",
+			},
+		},
+	}
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err == nil {
+		t.Errorf("expected error for variants sharing the tag Card, got nil")
+	}
+}
+
+func TestMapFieldKeyMustBeStringOrInteger(t *testing.T) {
+	types := map[string]*spec.Type{
+		"Catalog": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				Fields: map[string]spec.Field{
+					"prices": {
+						Ref:         spec.TypeRef{Name: "float"},
+						Cardinality: spec.Map,
+						Key:         &spec.TypeRef{Name: "boolean"},
+					},
+				},
+			},
+		},
+	}
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err == nil {
+		t.Fatal("expected error for boolean map key, got nil")
+	}
+}
+
+func TestAliasMustNamePrimitive(t *testing.T) {
+	types := map[string]*spec.Type{
+		"User": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				Fields: map[string]spec.Field{
+					"id": {Ref: spec.TypeRef{Name: "UserID"}},
+				},
+			},
+		},
+		"UserID": {Kind: spec.Alias, AliasType: &spec.AliasType{Target: spec.TypeRef{Name: "string"}}},
+		"Owner":  {Kind: spec.Alias, AliasType: &spec.AliasType{Target: spec.TypeRef{Name: "User"}}},
+	}
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err == nil {
+		t.Fatal("expected error for alias of an object type, got nil")
+	}
+	delete(types, "Owner")
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err != nil {
+		t.Fatalf("expected valid API spec, got error: %v", err)
+	}
+}
+
+func TestGenericTypeArity(t *testing.T) {
+	types := map[string]*spec.Type{
+		"Page": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				TypeParams: []string{"T"},
+				Fields: map[string]spec.Field{
+					"items": {Ref: spec.TypeRef{Name: "T", TypeParam: true}, Cardinality: spec.Multiple},
+				},
+			},
+		},
+	}
+
+	valid := spec.TypeRef{Name: "Page", Args: []spec.TypeRef{{Name: "string"}}}
+	if err := validateField(spec.Field{Ref: valid}, types); err != nil {
+		t.Fatalf("expected valid API spec, got error: %v", err)
+	}
+
+	invalid := []spec.TypeRef{
+		{Name: "Page"},
+		{Name: "Page", Args: []spec.TypeRef{{Name: "string"}, {Name: "integer"}}},
+		{Name: "Page", Args: []spec.TypeRef{{Name: "T", TypeParam: true}}},
+	}
+	for _, ref := range invalid {
+		if err := validateField(spec.Field{Ref: ref}, types); err == nil {
+			t.Errorf("expected error for %s, got nil", ref)
+		}
+	}
+}
+
+func TestStringDescribesTypeParams(t *testing.T) {
+	types := map[string]*spec.Type{
+		"Page": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				TypeParams: []string{"T"},
+				Fields: map[string]spec.Field{
+					"items": {Ref: spec.TypeRef{Name: "T", TypeParam: true}, Cardinality: spec.Multiple},
+				},
+			},
+		},
+	}
+	endpoints := []spec.Endpoint{
+		{
+			Name:      "CreatePage",
+			Method:    spec.Post,
+			Path:      spec.NewPathTemplate("/pages"),
+			Input:     &spec.InputShape{Body: &spec.TypeRef{Name: "Page", Args: []spec.TypeRef{{Name: "string"}}}},
+			Responses: []spec.Response{{Code: 204}},
+		},
+	}
+	api, err := spec.NewAPISpec("TestAPI", "http://localhost:1", endpoints, types)
+	if err != nil {
+		t.Fatalf("expected valid API spec, got error: %v", err)
+	}
+	if out := api.String(); !strings.Contains(out, "- items: []T") {
+		t.Errorf("expected the type param field to be printed, got:\n%s", out)
+	}
+}
+
+func TestConstraintMustFitFieldType(t *testing.T) {
+	valid := []spec.Field{
+		{Ref: spec.TypeRef{Name: "string"}, Constraints: []spec.Constraint{{Kind: spec.MinLength, Value: "1"}, {Kind: spec.Pattern, Value: "^[a-z]+$"}}},
+		{Ref: spec.TypeRef{Name: "integer"}, Constraints: []spec.Constraint{{Kind: spec.Min, Value: "0"}, {Kind: spec.Max, Value: "150"}}},
+		{Ref: spec.TypeRef{Name: "string"}, Cardinality: spec.Multiple, Constraints: []spec.Constraint{{Kind: spec.MinItems, Value: "1"}}},
+	}
+	for _, field := range valid {
+		if err := validateField(field, nil); err != nil {
+			t.Errorf("expected valid API spec for %v, got error: %v", field.Constraints, err)
+		}
+	}
+
+	invalid := []spec.Field{
+		{Ref: spec.TypeRef{Name: "integer"}, Constraints: []spec.Constraint{{Kind: spec.Pattern, Value: "^[a-z]+$"}}},
+		{Ref: spec.TypeRef{Name: "integer"}, Constraints: []spec.Constraint{{Kind: spec.Min, Value: "0.5"}}},
+		{Ref: spec.TypeRef{Name: "string"}, Constraints: []spec.Constraint{{Kind: spec.MinItems, Value: "1"}}},
+		{Ref: spec.TypeRef{Name: "string"}, Constraints: []spec.Constraint{{Kind: spec.MinLength, Value: "5"}, {Kind: spec.MaxLength, Value: "1"}}},
+		{Ref: spec.TypeRef{Name: "string"}, Constraints: []spec.Constraint{{Kind: spec.Pattern, Value: "("}}},
+	}
+	for _, field := range invalid {
+		if err := validateField(field, nil); err == nil {
+			t.Errorf("expected error for %v on %s, got nil", field.Constraints, field.Ref)
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	annotated := spec.Field{
+		Ref: spec.TypeRef{Name: "string"},
+		Annotations: spec.Annotations{
+			"column":   {Args: []spec.Value{{Kind: spec.StringValue, Literal: "name"}}},
+			"internal": {},
+		},
+	}
+	if err := validateField(annotated, nil); err != nil {
+		t.Errorf("expected unknown annotations to be kept as metadata, got error: %v", err)
+	}
+
+	unknown := spec.Field{Ref: spec.TypeRef{Name: "integer"}, Constraints: []spec.Constraint{{Kind: "between", Value: "1"}}}
+	if err := validateField(unknown, nil); err == nil {
+		t.Errorf("expected error for unknown constraint, got nil")
+	}
+	malformed := spec.Field{Ref: spec.TypeRef{Name: "integer"}, Constraints: []spec.Constraint{{Kind: spec.Min, Value: "one"}}}
+	if err := validateField(malformed, nil); err == nil {
+		t.Errorf("expected error for constraint with a malformed argument, got nil")
+	}
+}
+
+func TestDefaultMustMatchFieldType(t *testing.T) {
+	types := map[string]*spec.Type{
+		"Sort": {Kind: spec.Enum, EnumType: &spec.EnumType{Members: []string{"newest", "oldest"}}},
+	}
+
+	valid := []spec.Field{
+		{Ref: spec.TypeRef{Name: "integer"}, Optional: true, Default: &spec.Value{Kind: spec.NumberValue, Literal: "20"}},
+		{Ref: spec.TypeRef{Name: "Sort"}, Optional: true, Default: &spec.Value{Kind: spec.SymbolValue, Literal: "oldest"}},
+		{Ref: spec.TypeRef{Name: "boolean"}, Optional: true, Default: &spec.Value{Kind: spec.SymbolValue, Literal: "false"}},
+	}
+	for _, field := range valid {
+		if err := validateField(field, types); err != nil {
+			t.Errorf("expected valid API spec for default %s, got error: %v", field.Default, err)
+		}
+	}
+
+	invalid := []spec.Field{
+		{Ref: spec.TypeRef{Name: "integer"}, Optional: true, Default: &spec.Value{Kind: spec.StringValue, Literal: "20"}},
+		{Ref: spec.TypeRef{Name: "integer"}, Optional: true, Default: &spec.Value{Kind: spec.NumberValue, Literal: "2.5"}},
+		{Ref: spec.TypeRef{Name: "Sort"}, Optional: true, Default: &spec.Value{Kind: spec.SymbolValue, Literal: "random"}},
+		{Ref: spec.TypeRef{Name: "integer"}, Default: &spec.Value{Kind: spec.NumberValue, Literal: "20"}},
+	}
+	for _, field := range invalid {
+		if err := validateField(field, types); err == nil {
+			t.Errorf("expected error for default %s of %s, got nil", field.Default, field.Ref)
+		}
+	}
+}
+
+func TestParamsCannotHaveDefaults(t *testing.T) {
+	endpoint := spec.Endpoint{
+		Path: spec.NewPathTemplate("/users/{id}"),
+		Input: &spec.InputShape{
+			Params: map[string]spec.Field{
+				"id": {Ref: spec.TypeRef{Name: "integer"}, Default: &spec.Value{Kind: spec.NumberValue, Literal: "1"}},
+			},
+		},
+	}
+	if err := validateEndpoint(endpoint, nil); err == nil {
+		t.Errorf("expected error for param with a default, got nil")
+	}
+}
+
+func TestDeprecationWarnings(t *testing.T) {
+	types := map[string]*spec.Type{
+		"OldAddress": {
+			Kind:       spec.Object,
+			Deprecated: &spec.Deprecation{Message: "use Address"},
+			ObjectType: &spec.ObjectType{Fields: map[string]spec.Field{}},
+		},
+		"User": {
+			Kind: spec.Object,
+			ObjectType: &spec.ObjectType{
+				Fields: map[string]spec.Field{
+					"home": {Ref: spec.TypeRef{Name: "OldAddress"}},
+					"address": {
+						Ref:        spec.TypeRef{Name: "OldAddress"},
+						Deprecated: &spec.Deprecation{Message: "use home"},
+					},
+				},
+			},
+		},
+	}
+	api, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types)
+	if err != nil {
+		t.Fatalf("expected valid API spec, got error: %v", err)
+	}
+	warnings := api.DeprecationWarnings()
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning for field User.home, got %v", warnings)
+	}
+}
+
+func TestQualifiedNamesMustNotCollide(t *testing.T) {
+	newObject := func() *spec.Type {
+		return &spec.Type{Kind: spec.Object, ObjectType: &spec.ObjectType{Fields: map[string]spec.Field{}}}
+	}
+	types := map[string]*spec.Type{
+		"Error":         newObject(),
+		"billing.Error": newObject(),
+	}
+	api, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types)
+	if err != nil {
+		t.Fatalf("expected valid API spec, got error: %v", err)
+	}
+	if err := api.CheckNaming(spec.Naming{Prefixes: map[string]string{"billing": ""}}); err == nil {
+		t.Errorf("expected collision when billing has no prefix, got nil")
+	}
+
+	types["BillingError"] = newObject()
+	if _, err := spec.NewAPISpec("TestAPI", "http://localhost:1", nil, types); err == nil {
+		t.Errorf("expected billing.Error to collide with BillingError, got nil")
+	}
+}
+
+func TestHeadersMustBePrimitive(t *testing.T) {
+	if err := validateEndpoint(spec.Endpoint{Input: &spec.InputShape{Headers: map[string]spec.Field{
+		"X-Request-Id": {Ref: spec.TypeRef{Name: "uuid"}},
+		"If-Match":     {Ref: spec.TypeRef{Name: "string"}, Optional: true},
+	}}}, nil); err != nil {
+		t.Errorf("expected valid API spec, got error: %v", err)
+	}
+
+	invalid := []map[string]spec.Field{
+		{"X-User": {Ref: spec.TypeRef{Name: "User"}}},
+		{"X-Ids": {Ref: spec.TypeRef{Name: "uuid"}, Cardinality: spec.Multiple}},
+		{"X Id": {Ref: spec.TypeRef{Name: "uuid"}}},
+		{"X-Id": {Ref: spec.TypeRef{Name: "uuid"}}, "x-id": {Ref: spec.TypeRef{Name: "uuid"}}},
+	}
+	for _, headers := range invalid {
+		if err := validateEndpoint(spec.Endpoint{Input: &spec.InputShape{Headers: headers}}, nil); err == nil {
+			t.Errorf("expected error for headers %v, got nil", headers)
+		}
+	}
+}
+
+func TestCookies(t *testing.T) {
+	// Unlike header names, cookie names are case-sensitive.
+	if err := validateEndpoint(spec.Endpoint{Input: &spec.InputShape{Cookies: map[string]spec.Field{
+		"session": {Ref: spec.TypeRef{Name: "string"}},
+		"Session": {Ref: spec.TypeRef{Name: "string"}, Optional: true},
+	}}}, nil); err != nil {
+		t.Errorf("expected valid API spec, got error: %v", err)
+	}
+
+	invalid := []map[string]spec.Field{
+		{"user": {Ref: spec.TypeRef{Name: "User"}}},
+		{"ids": {Ref: spec.TypeRef{Name: "uuid"}, Cardinality: spec.Multiple}},
+		{"my id": {Ref: spec.TypeRef{Name: "uuid"}}},
+	}
+	for _, cookies := range invalid {
+		if err := validateEndpoint(spec.Endpoint{Input: &spec.InputShape{Cookies: cookies}}, nil); err == nil {
+			t.Errorf("expected error for cookies %v, got nil", cookies)
+		}
+	}
+}
+
+func TestFilesOnlyInMultipartBodies(t *testing.T) {
+	upload := map[string]spec.Field{
+		"file":        {Ref: spec.TypeRef{Name: "file"}},
+		"attachments": {Ref: spec.TypeRef{Name: "file"}, Cardinality: spec.Multiple},
+		"description": {Ref: spec.TypeRef{Name: "string"}, Optional: true},
+	}
+	nested := map[string]spec.Field{
+		"files": {Ref: spec.TypeRef{Elem: &spec.TypeRef{Name: "file"}}, Cardinality: spec.Multiple},
+	}
+	tests := []struct {
+		name        string
+		contentType string
+		fields      map[string]spec.Field
+		wantErr     bool
+	}{
+		{"files in multipart body", spec.MultipartContentType, upload, false},
+		{"file in JSON body", spec.JSONContentType, upload, true},
+		{"nested list in multipart body", spec.MultipartContentType, nested, true},
+	}
+	for _, tt := range tests {
+		endpoint := spec.Endpoint{
+			Method: spec.Post,
+			Input:  &spec.InputShape{Body: &spec.TypeRef{Name: "UploadBody"}, ContentType: tt.contentType},
+		}
+		types := map[string]*spec.Type{
+			"UploadBody": {Kind: spec.Object, ObjectType: &spec.ObjectType{Fields: tt.fields}},
+		}
+		if err := validateEndpoint(endpoint, types); (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error %t, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestBodyContentTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{spec.JSONContentType, "Nested", false},
+		{"application/merge-patch+json", "Nested", false},
+		{spec.FormContentType, "Flat", false},
+		{"application/xml; charset=utf-8", "string", false},
+		{"application/octet-stream", "bytes", false},
+		{spec.FormContentType, "Nested", true},
+		{spec.FormContentType, "string", true},
+		{"application/xml", "Flat", true},
+		{"not a media type", "string", true},
+	}
+	for _, tt := range tests {
+		endpoint := spec.Endpoint{
+			Method: spec.Post,
+			Input:  &spec.InputShape{Body: &spec.TypeRef{Name: tt.body}, ContentType: tt.contentType},
+		}
+		types := map[string]*spec.Type{
+			"Flat": {Kind: spec.Object, ObjectType: &spec.ObjectType{Fields: map[string]spec.Field{
+				"name": {Ref: spec.TypeRef{Name: "string"}},
+				"tags": {Ref: spec.TypeRef{Name: "string"}, Cardinality: spec.Multiple},
+			}}},
+			"Nested": {Kind: spec.Object, ObjectType: &spec.ObjectType{Fields: map[string]spec.Field{
+				"flat": {Ref: spec.TypeRef{Name: "Flat"}},
+			}}},
+		}
+		if err := validateEndpoint(endpoint, types); (err != nil) != tt.wantErr {
+			t.Errorf("%s body %s: expected error %t, got %v", tt.contentType, tt.body, tt.wantErr, err)
+		}
+	}
+}
+
+func TestBinaryResponses(t *testing.T) {
+	if err := validateEndpoint(spec.Endpoint{Responses: []spec.Response{
+		{Code: 200, ContentType: "application/pdf"},
+		{Code: 404, Ref: &spec.TypeRef{Name: "string"}},
+	}}, nil); err != nil {
+		t.Errorf("expected valid API spec, got error: %v", err)
+	}
+	if err := validateEndpoint(spec.Endpoint{Responses: []spec.Response{
+		{Code: 200, Ref: &spec.TypeRef{Name: "string"}},
+		{Code: 404, ContentType: "text/plain"},
+	}}, nil); err == nil {
+		t.Errorf("expected error for binary error response, got nil")
+	}
+	if err := validateEndpoint(spec.Endpoint{Responses: []spec.Response{{Code: 200, ContentType: "pdf file"}}}, nil); err == nil {
+		t.Errorf("expected error for invalid content type, got nil")
+	}
+}
+
+func TestStreamResponses(t *testing.T) {
+	if err := validateEndpoint(spec.Endpoint{Responses: []spec.Response{
+		{Code: 200, Ref: &spec.TypeRef{Name: "string"}, Stream: spec.NDJSON},
+		{Code: 404, Ref: &spec.TypeRef{Name: "string"}},
+	}}, nil); err != nil {
+		t.Errorf("expected valid API spec, got error: %v", err)
+	}
+	invalid := [][]spec.Response{
+		{{Code: 200, Ref: &spec.TypeRef{Name: "string"}}, {Code: 404, Ref: &spec.TypeRef{Name: "string"}, Stream: spec.SSE}},
+		{{Code: 200, Ref: &spec.TypeRef{Name: "string"}, Stream: "websocket"}},
+		{{Code: 200, Ref: &spec.TypeRef{Name: "string"}, Stream: spec.SSE, Headers: map[string]spec.Field{"X-Count": {Ref: spec.TypeRef{Name: "integer"}}}}},
+	}
+	for _, responses := range invalid {
+		if err := validateEndpoint(spec.Endpoint{Responses: responses}, nil); err == nil {
+			t.Errorf("expected error for responses %v, got nil", responses)
+		}
+	}
+}
+
+func TestChannels(t *testing.T) {
+	tests := []struct {
+		channel spec.Channel
+		wantErr bool
+	}{
+		{spec.Channel{Name: "OrderFeed", Path: spec.NewPathTemplate("/ws/orders"), Client: spec.TypeRef{Name: "string"}, Server: spec.TypeRef{Name: "integer"}}, false},
+		{spec.Channel{Name: "OrderFeed", Path: spec.NewPathTemplate("/ws/orders/{id}"), Client: spec.TypeRef{Name: "string"}, Server: spec.TypeRef{Name: "string"}}, true},
+		{spec.Channel{Name: "OrderFeed", Path: spec.NewPathTemplate("/ws/orders"), Client: spec.TypeRef{Name: "Missing"}, Server: spec.TypeRef{Name: "string"}}, true},
+		{spec.Channel{Name: "OrderFeed", Path: spec.NewPathTemplate("/ws/orders"), Client: spec.TypeRef{Name: "string"}, Server: spec.TypeRef{Name: "file"}}, true},
+	}
+	for _, tt := range tests {
+		_, err := spec.NewAPISpecWithChannels("TestAPI", "http://localhost:1", nil, []spec.Channel{tt.channel}, map[string]*spec.Type{})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("channel %s at %s: expected error %t, got %v", tt.channel.Name, tt.channel.Path, tt.wantErr, err)
+		}
+	}
+}
+
+func TestResponseHeaders(t *testing.T) {
+	if err := validateEndpoint(spec.Endpoint{Responses: []spec.Response{
+		{Code: 201, Ref: &spec.TypeRef{Name: "User"}, Headers: map[string]spec.Field{"Location": {Ref: spec.TypeRef{Name: "url"}}}},
+	}}, nil); err != nil {
+		t.Errorf("expected valid API spec, got error: %v", err)
+	}
+	invalid := []map[string]spec.Field{
+		{"X-User": {Ref: spec.TypeRef{Name: "User"}}},
+		{"X-Count": {Ref: spec.TypeRef{Name: "integer"}, Optional: true, Default: &spec.Value{Kind: spec.NumberValue, Literal: "0"}}},
+	}
+	for _, headers := range invalid {
+		created := spec.Endpoint{Responses: []spec.Response{{Code: 201, Ref: &spec.TypeRef{Name: "User"}, Headers: headers}}}
+		if err := validateEndpoint(created, nil); err == nil {
+			t.Errorf("expected error for response headers %v, got nil", headers)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package spec
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DeprecationWarnings describes the deprecated types and enum members that
+// are still referenced by items which are not deprecated themselves.
+func (api *APISpec) DeprecationWarnings() []string {
+	var warnings []string
+	var checkRef func(ref TypeRef, from string)
+	checkRef = func(ref TypeRef, from string) {
+		if ref.IsArray() {
+			checkRef(*ref.Elem, from)
+			return
+		}
+		for _, arg := range ref.Args {
+			checkRef(arg, from)
+		}
+		if ref.TypeParam {
+			return
+		}
+		if typ, ok := api.ResolveTypeRef(ref); ok && typ.Deprecated != nil {
+			warnings = append(warnings, fmt.Sprintf("type %s is deprecated but referenced by %s: %s", ref.Name, from, typ.Deprecated))
+		}
+	}
+	checkField := func(field Field, from string) {
+		if field.Deprecated != nil {
+			return
+		}
+		checkRef(field.Ref, from)
+		if field.Key != nil {
+			checkRef(*field.Key, from)
+		}
+		if field.Default == nil {
+			return
+		}
+		if typ, ok := api.ResolveTypeRef(field.Ref); ok && typ.Kind == Enum {
+			if deprecation, ok := typ.EnumType.Deprecated[field.Default.Literal]; ok {
+				warnings = append(warnings, fmt.Sprintf("enum member %s.%s is deprecated but is the default of %s: %s", field.Ref.Name, field.Default.Literal, from, deprecation))
+			}
+		}
+	}
+
+	for typeName, typ := range api.Types {
+		if typ.Deprecated != nil {
+			continue
+		}
+		switch typ.Kind {
+		case Object:
+			for fieldName, field := range typ.ObjectType.Fields {
+				checkField(field, fmt.Sprintf("field %s.%s", typeName, fieldName))
+			}
+			for _, parent := range typ.ObjectType.Parents {
+				checkRef(parent, "type "+typeName)
+			}
+			if typ.ObjectType.Derivation != nil {
+				checkRef(typ.ObjectType.Derivation.Base, "type "+typeName)
+			}
+		case Union:
+			for _, variant := range typ.UnionType.Variants {
+				checkRef(variant, "union "+typeName)
+			}
+		case Alias:
+			checkRef(typ.AliasType.Target, "alias "+typeName)
+		}
+	}
+
+	for _, endpoint := range api.Endpoints {
+		if endpoint.Deprecated != nil {
+			continue
+		}
+		if endpoint.Input != nil {
+			for paramName, field := range endpoint.Input.Params {
+				checkField(field, fmt.Sprintf("endpoint %s param %s", endpoint.Name, paramName))
+			}
+			for queryName, field := range endpoint.Input.Query {
+				checkField(field, fmt.Sprintf("endpoint %s query %s", endpoint.Name, queryName))
+			}
+			for headerName, field := range endpoint.Input.Headers {
+				checkField(field, fmt.Sprintf("endpoint %s header %s", endpoint.Name, headerName))
+			}
+			for cookieName, field := range endpoint.Input.Cookies {
+				checkField(field, fmt.Sprintf("endpoint %s cookie %s", endpoint.Name, cookieName))
+			}
+			if endpoint.Input.Body != nil {
+				checkRef(*endpoint.Input.Body, fmt.Sprintf("endpoint %s body", endpoint.Name))
+			}
+		}
+		for _, resp := range endpoint.Responses {
+			if resp.Ref != nil {
+				checkRef(*resp.Ref, fmt.Sprintf("endpoint %s response %d", endpoint.Name, resp.Code))
+			}
+			for headerName, field := range resp.Headers {
+				checkField(field, fmt.Sprintf("endpoint %s response %d header %s", endpoint.Name, resp.Code, headerName))
+			}
+		}
+	}
+
+	for _, channel := range api.Channels {
+		if channel.Deprecated != nil {
+			continue
+		}
+		checkRef(channel.Client, fmt.Sprintf("channel %s client messages", channel.Name))
+		checkRef(channel.Server, fmt.Sprintf("channel %s server messages", channel.Name))
+	}
+
+	sort.Strings(warnings)
+	return warnings
+}
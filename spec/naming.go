@@ -0,0 +1,94 @@
+package spec
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// QualifiedName returns the name of a type declared in pkg, which may be
+// empty.
+func QualifiedName(pkg, name string) string {
+	if pkg == "" {
+		return name
+	}
+	return pkg + "." + name
+}
+
+// SplitQualifiedName splits a qualified type name into its package and the
+// name the type was declared with.
+func SplitQualifiedName(name string) (pkg, local string) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+// VariantTag returns the discriminator value identifying variant in a
+// union, which is the name its type was declared with.
+func VariantTag(variant TypeRef) string {
+	_, local := SplitQualifiedName(variant.Name)
+	return local
+}
+
+// Naming maps qualified type names to identifiers in generated code. A type
+// declared in a package is named by the package's prefix followed by its
+// name. Packages without a configured prefix use their name, capitalized
+// segment by segment, so billing.Invoice becomes BillingInvoice.
+type Naming struct {
+	Prefixes map[string]string
+	// Reserved holds the identifiers of the helper types that generated
+	// code declares, which no type may be mapped to.
+	Reserved []string
+}
+
+func (n Naming) prefix(pkg string) string {
+	if prefix, ok := n.Prefixes[pkg]; ok {
+		return prefix
+	}
+	prefix := ""
+	for _, part := range strings.Split(pkg, ".") {
+		if part != "" {
+			prefix += strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return prefix
+}
+
+// TypeName returns the identifier of the type called name.
+func (n Naming) TypeName(name string) string {
+	pkg, local := SplitQualifiedName(name)
+	if pkg == "" {
+		return name
+	}
+	prefix := n.prefix(pkg)
+	if prefix == "" {
+		return local
+	}
+	return prefix + strings.ToUpper(local[:1]) + local[1:]
+}
+
+// CheckNaming reports distinct types that n maps to the same identifier, and
+// types mapped to a reserved identifier.
+func (api *APISpec) CheckNaming(n Naming) error {
+	names := make([]string, 0, len(api.Types))
+	for name := range api.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := make(map[string]string)
+	for _, name := range names {
+		ident := n.TypeName(name)
+		for _, reserved := range n.Reserved {
+			if ident == reserved {
+				return fmt.Errorf("type %s collides with the generated helper type %s", name, ident)
+			}
+		}
+		if other, ok := seen[ident]; ok {
+			return fmt.Errorf("type %s collides with %s as %s", name, other, ident)
+		}
+		seen[ident] = name
+	}
+	return nil
+}
@@ -1,10 +1,18 @@
 package spec
 
+import "fmt"
+
 type TypeResolver interface {
 	IsPrimitive(TypeRef) bool
 	IsObject(TypeRef) bool
+	IsEnum(TypeRef) bool
+	IsUnion(TypeRef) bool
+	IsAlias(TypeRef) bool
 	PrimitiveOf(TypeRef) (PrimitiveType, bool)
 	ObjectOf(TypeRef) (*ObjectType, bool)
+	EnumOf(TypeRef) (*EnumType, bool)
+	UnionOf(TypeRef) (*UnionType, bool)
+	AliasOf(TypeRef) (*AliasType, bool)
 	MustResolve(TypeRef) *Type
 	IsOptional(Field) bool
 	ResolveSuccessResponse(Endpoint) *Response
@@ -18,8 +26,17 @@ func NewTypeResolver(api *APISpec) TypeResolver {
 	return &defaultTypeResolver{api: api}
 }
 
+// underlying resolves ref through any aliases.
+func (r *defaultTypeResolver) underlying(ref TypeRef) *Type {
+	typ, ok := r.api.ResolveUnderlyingType(ref)
+	if !ok {
+		panic(fmt.Sprintf("unresolved type reference: %s", ref.Name))
+	}
+	return typ
+}
+
 func (r *defaultTypeResolver) IsPrimitive(ref TypeRef) bool {
-	typ := r.api.ResolveTypeRefOrPanic(ref)
+	typ := r.underlying(ref)
 	return typ.Kind == Primitive
 }
 
@@ -28,8 +45,23 @@ func (r *defaultTypeResolver) IsObject(ref TypeRef) bool {
 	return typ.Kind == Object
 }
 
-func (r *defaultTypeResolver) PrimitiveOf(ref TypeRef) (PrimitiveType, bool) {
+func (r *defaultTypeResolver) IsEnum(ref TypeRef) bool {
 	typ := r.api.ResolveTypeRefOrPanic(ref)
+	return typ.Kind == Enum
+}
+
+func (r *defaultTypeResolver) IsUnion(ref TypeRef) bool {
+	typ := r.api.ResolveTypeRefOrPanic(ref)
+	return typ.Kind == Union
+}
+
+func (r *defaultTypeResolver) IsAlias(ref TypeRef) bool {
+	typ := r.api.ResolveTypeRefOrPanic(ref)
+	return typ.Kind == Alias
+}
+
+func (r *defaultTypeResolver) PrimitiveOf(ref TypeRef) (PrimitiveType, bool) {
+	typ := r.underlying(ref)
 	if typ.Kind != Primitive {
 		return 0, false
 	}
@@ -44,6 +76,30 @@ func (r *defaultTypeResolver) ObjectOf(ref TypeRef) (*ObjectType, bool) {
 	return typ.ObjectType, true
 }
 
+func (r *defaultTypeResolver) EnumOf(ref TypeRef) (*EnumType, bool) {
+	typ := r.api.ResolveTypeRefOrPanic(ref)
+	if typ.Kind != Enum {
+		return nil, false
+	}
+	return typ.EnumType, true
+}
+
+func (r *defaultTypeResolver) UnionOf(ref TypeRef) (*UnionType, bool) {
+	typ := r.api.ResolveTypeRefOrPanic(ref)
+	if typ.Kind != Union {
+		return nil, false
+	}
+	return typ.UnionType, true
+}
+
+func (r *defaultTypeResolver) AliasOf(ref TypeRef) (*AliasType, bool) {
+	typ := r.api.ResolveTypeRefOrPanic(ref)
+	if typ.Kind != Alias {
+		return nil, false
+	}
+	return typ.AliasType, true
+}
+
 func (r *defaultTypeResolver) MustResolve(ref TypeRef) *Type {
 	return r.api.ResolveTypeRefOrPanic(ref)
 }
@@ -1,5 +1,11 @@
 package spec
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
 type HTTPMethod int
 
 const (
@@ -32,16 +38,74 @@ type TypeKind string
 const (
 	Object    TypeKind = "object"
 	Primitive TypeKind = "primitive"
+	Enum      TypeKind = "enum"
+	Union     TypeKind = "union"
+	Alias     TypeKind = "alias"
 )
 
 type Type struct {
+	// Source is the file the type was declared in, if known.
+	Source        string
 	Kind          TypeKind
+	Doc           string
+	Annotations   Annotations
+	Deprecated    *Deprecation
 	ObjectType    *ObjectType
+	EnumType      *EnumType
+	UnionType     *UnionType
+	AliasType     *AliasType
 	PrimitiveType PrimitiveType
 }
 
+// ObjectType holds the fields of an object, including those inherited from
+// Parents. Derivation records the type operator an object was computed by,
+// if any.
 type ObjectType struct {
-	Fields map[string]Field
+	Fields     map[string]Field
+	TypeParams []string
+	Parents    []TypeRef
+	Derivation *Derivation
+}
+
+type DerivationOperator string
+
+const (
+	Pick    DerivationOperator = "Pick"
+	Omit    DerivationOperator = "Omit"
+	Partial DerivationOperator = "Partial"
+)
+
+// Derivation describes an object type computed from the fields of Base.
+type Derivation struct {
+	Operator DerivationOperator
+	Base     TypeRef
+	Fields   []string
+}
+
+func (d *Derivation) String() string {
+	if len(d.Fields) == 0 {
+		return fmt.Sprintf("%s<%s>", d.Operator, d.Base)
+	}
+	return fmt.Sprintf("%s<%s, %s>", d.Operator, d.Base, strings.Join(d.Fields, ", "))
+}
+
+// AliasType is a distinct named type whose values are those of Target.
+type AliasType struct {
+	Target TypeRef
+}
+
+type EnumType struct {
+	Members []string
+	// Deprecated and MemberAnnotations are keyed by member.
+	Deprecated        map[string]*Deprecation
+	MemberAnnotations map[string]Annotations
+}
+
+// UnionType is a discriminated union of object types. The discriminator
+// field of each variant carries the variant's type name.
+type UnionType struct {
+	Variants      []TypeRef
+	Discriminator string
 }
 
 type Cardinality int
@@ -49,13 +113,134 @@ type Cardinality int
 const (
 	Single Cardinality = iota
 	Multiple
+	Map
 )
 
 type Field struct {
 	Ref         TypeRef
 	Cardinality Cardinality
+	// Key is the map key type when Cardinality is Map.
+	Key         *TypeRef
 	Optional    bool
 	Nullable    bool
+	Constraints []Constraint
+	Doc         string
+	Annotations Annotations
+	Deprecated  *Deprecation
+	// Default is the value assumed for an optional field that is absent.
+	Default *Value
+}
+
+// SameType reports whether f and other hold values of the same type.
+func (f Field) SameType(other Field) bool {
+	if f.Cardinality != other.Cardinality || f.Ref.String() != other.Ref.String() {
+		return false
+	}
+	if f.Key == nil || other.Key == nil {
+		return f.Key == other.Key
+	}
+	return f.Key.Name == other.Key.Name
+}
+
+// Annotation is metadata attached to a declaration with @name(args...).
+// Named holds the arguments given as name=value.
+type Annotation struct {
+	Args  []Value
+	Named map[string]Value
+}
+
+// Deprecation marks an item that consumers should stop using. Message
+// usually names its replacement.
+type Deprecation struct {
+	Message string
+	Since   string
+}
+
+func (d *Deprecation) String() string {
+	msg := d.Message
+	if msg == "" {
+		msg = "do not use"
+	}
+	if d.Since != "" {
+		msg += fmt.Sprintf(" (since %s)", d.Since)
+	}
+	return msg
+}
+
+// Annotations maps annotation names to their arguments. Annotations the
+// language does not interpret are kept for generators and plugins.
+type Annotations map[string]Annotation
+
+type ValueKind int
+
+const (
+	NumberValue ValueKind = iota
+	StringValue
+	// SymbolValue is a bare identifier: true, false or an enum member.
+	SymbolValue
+)
+
+// Value is a literal written in the DSL. Literal holds the number or symbol
+// as written, or the unquoted string.
+type Value struct {
+	Kind    ValueKind
+	Literal string
+}
+
+func (v Value) String() string {
+	if v.Kind == StringValue {
+		return fmt.Sprintf("%q", v.Literal)
+	}
+	return v.Literal
+}
+
+type ConstraintKind string
+
+const (
+	MinLength ConstraintKind = "minLength"
+	MaxLength ConstraintKind = "maxLength"
+	Min       ConstraintKind = "min"
+	Max       ConstraintKind = "max"
+	Pattern   ConstraintKind = "pattern"
+	MinItems  ConstraintKind = "minItems"
+	MaxItems  ConstraintKind = "maxItems"
+)
+
+// Constraint restricts the values a field accepts. Value is the regular
+// expression of a Pattern constraint and a number literal otherwise.
+type Constraint struct {
+	Kind  ConstraintKind
+	Value string
+}
+
+// Message describes the requirement of c as it is reported when a value
+// violates it.
+func (c Constraint) Message() string {
+	switch c.Kind {
+	case MinLength:
+		return fmt.Sprintf("must be at least %s characters long", c.Value)
+	case MaxLength:
+		return fmt.Sprintf("must be at most %s characters long", c.Value)
+	case Min:
+		return fmt.Sprintf("must be at least %s", c.Value)
+	case Max:
+		return fmt.Sprintf("must be at most %s", c.Value)
+	case Pattern:
+		return fmt.Sprintf("must match pattern %s", c.Value)
+	case MinItems:
+		return fmt.Sprintf("must contain at least %s items", c.Value)
+	case MaxItems:
+		return fmt.Sprintf("must contain at most %s items", c.Value)
+	default:
+		return "is invalid"
+	}
+}
+
+func (c Constraint) String() string {
+	if c.Kind == Pattern {
+		return fmt.Sprintf("@%s(%q)", c.Kind, c.Value)
+	}
+	return fmt.Sprintf("@%s(%s)", c.Kind, c.Value)
 }
 
 type PrimitiveType int
@@ -65,6 +250,16 @@ const (
 	Integer
 	Float
 	Boolean
+	DateTime
+	Date
+	UUID
+	Bytes
+	Int64
+	Decimal
+	Email
+	URL
+	// File is an uploaded file, only allowed in multipart bodies.
+	File
 )
 
 func (p PrimitiveType) String() string {
@@ -77,16 +272,133 @@ func (p PrimitiveType) String() string {
 		return "float"
 	case Boolean:
 		return "boolean"
+	case DateTime:
+		return "datetime"
+	case Date:
+		return "date"
+	case UUID:
+		return "uuid"
+	case Bytes:
+		return "bytes"
+	case Int64:
+		return "int64"
+	case Decimal:
+		return "decimal"
+	case Email:
+		return "email"
+	case URL:
+		return "url"
+	case File:
+		return "file"
 	default:
 		return "unknown"
 	}
 }
 
+// TypeRef references a named type, or an array of another TypeRef when
+// Elem is set. Args holds the type arguments of a generic type, and
+// TypeParam marks a reference to a type parameter of the enclosing type.
 type TypeRef struct {
-	Name string
+	Name      string
+	Elem      *TypeRef
+	Args      []TypeRef
+	TypeParam bool
+}
+
+func (r TypeRef) IsArray() bool {
+	return r.Elem != nil
+}
+
+// Base returns the innermost named type of a possibly nested array reference.
+func (r TypeRef) Base() TypeRef {
+	for r.Elem != nil {
+		r = *r.Elem
+	}
+	return r
+}
+
+// Substitute returns r with args in place of the type parameters params.
+func (r TypeRef) Substitute(params []string, args []TypeRef) TypeRef {
+	if r.Elem != nil {
+		elem := r.Elem.Substitute(params, args)
+		r.Elem = &elem
+		return r
+	}
+	if r.TypeParam {
+		if i := slices.Index(params, r.Name); i >= 0 && i < len(args) {
+			return args[i]
+		}
+		return r
+	}
+	if len(r.Args) > 0 {
+		substituted := make([]TypeRef, len(r.Args))
+		for i, arg := range r.Args {
+			substituted[i] = arg.Substitute(params, args)
+		}
+		r.Args = substituted
+	}
+	return r
+}
+
+func (r TypeRef) String() string {
+	if r.Elem != nil {
+		return "[" + r.Elem.String() + "]"
+	}
+	if len(r.Args) > 0 {
+		args := make([]string, len(r.Args))
+		for i, arg := range r.Args {
+			args[i] = arg.String()
+		}
+		return r.Name + "<" + strings.Join(args, ", ") + ">"
+	}
+	return r.Name
 }
 
 type Response struct {
 	Code int
-	Ref  *TypeRef
+	// Ref is the type of a JSON response body. It is nil for binary
+	// responses.
+	Ref *TypeRef
+	// ContentType is the media type of a binary response, whose body is
+	// streamed as it is. It is empty for JSON responses.
+	ContentType string
+	// Stream is the format of a response streaming events of type Ref. It
+	// is empty for responses that are not streamed.
+	Stream StreamFormat
+	// Headers holds the response headers, keyed by header name.
+	Headers map[string]Field
+}
+
+// StreamFormat is how a streamed response encodes its events.
+type StreamFormat string
+
+const (
+	// SSE streams are server-sent events with JSON data.
+	SSE StreamFormat = "sse"
+	// NDJSON streams are newline-delimited JSON values.
+	NDJSON StreamFormat = "ndjson"
+)
+
+// ContentType returns the media type of a stream in format f.
+func (f StreamFormat) ContentType() string {
+	if f == NDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/event-stream"
+}
+
+// IsStream reports whether the response streams events.
+func (r Response) IsStream() bool {
+	return r.Stream != ""
+}
+
+// IsBinary reports whether the response body is streamed as it is rather
+// than decoded as JSON.
+func (r Response) IsBinary() bool {
+	return r.ContentType != ""
+}
+
+// IsEmpty reports whether the response has no body.
+func (r Response) IsEmpty() bool {
+	return r.Ref == nil && !r.IsBinary()
 }